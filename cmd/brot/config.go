@@ -3,18 +3,49 @@ package main
 import (
 	"flag"
 	"fmt"
+	"github.com/PieterD/brot/pkg/mandelbrot"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	ConfigFile string
-	OutputFile string
+	ConfigFile    string
+	OutputFile    string
+	At            time.Duration
+	JPEGQuality   int
+	OptimizeGIF   bool
+	FirstFrame    int
+	LastFrame     int
+	CountFrames   bool
+	CheckpointDir string
+	Draft         float64
+	ContactSheet  string
+	PaletteFile   string
+	ExportPalette string
+	Dither        string
+	GIFPalette    string
 }
 
 func NewConfigFromFlags() (Config, bool) {
 	var cfg Config
 	flag.StringVar(&cfg.ConfigFile, "config", "config.json", "Filename of the input config file")
-	flag.StringVar(&cfg.OutputFile, "output", "mandelbrot.gif", "Filename of the output GIF file")
+	flag.StringVar(&cfg.OutputFile, "output", "mandelbrot.gif", "Filename of the output file; the extension (.gif, .apng, .avi, .png, .jpg, .jpeg or .y4m) selects the format. "+
+		"A directory (ending in a path separator) receives a PNG image sequence, and - writes a Y4M stream to stdout")
+	flag.DurationVar(&cfg.At, "at", 0, "Position in the animation to render, for still image output")
+	flag.IntVar(&cfg.JPEGQuality, "jpeg-quality", 90, "Quality of JPEG and AVI output, between 1 and 100")
+	flag.BoolVar(&cfg.OptimizeGIF, "optimize-gif", true, "Only store the changed part of every GIF frame, and merge identical frames")
+	flag.IntVar(&cfg.FirstFrame, "first-frame", 0, "Index of the first frame to render, for splitting an animation over multiple runs")
+	flag.IntVar(&cfg.LastFrame, "last-frame", -1, "Index of the last frame to render (inclusive); -1 renders up to the end")
+	flag.BoolVar(&cfg.CountFrames, "count-frames", false, "Print the number of frames in the animation, and exit")
+	flag.StringVar(&cfg.CheckpointDir, "checkpoint-dir", "", "Work directory in which rendered frames are stored; re-running the same command skips the frames that are already finished")
+	flag.Float64Var(&cfg.Draft, "draft", 0, "Render a quick preview, with resolution, FPS and iterations multiplied by this factor (between 0 and 1); 0 renders in full")
+	flag.StringVar(&cfg.ContactSheet, "contact-sheet", "contact-sheet.png", "Filename of the PNG contact sheet of all keyframes, written in draft mode")
+	flag.StringVar(&cfg.PaletteFile, "palette", "", "Palette file (.map, .gpl, .ugr, .ucl or .csv) replacing the colors of the configured palette")
+	flag.StringVar(&cfg.ExportPalette, "export-palette", "", "Write the colors of the palette, without the interior color, to this file (.map, .gpl, .ugr or .csv), and exit")
+	flag.StringVar(&cfg.Dither, "dither", mandelbrot.DitherNone, "Dithering of GIF output: none, bayer (recommended, as its pattern stays in place while the camera moves) or floyd-steinberg (only for still or palette cycling views); dithered frames are rendered in true color first")
+	flag.StringVar(&cfg.GIFPalette, "gif-palette", mandelbrot.GIFPaletteGlobal, "Palette of true color GIF frames: global, sampled from the whole animation, or adaptive, chosen per frame")
 	flag.Parse()
 	if err := cfg.Validate(); err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "validating config: %v\n", err)
@@ -31,5 +62,71 @@ func (cfg Config) Validate() error {
 	if cfg.OutputFile == "" {
 		return fmt.Errorf("missing -output")
 	}
+	switch cfg.OutputFormat() {
+	case "gif", "apng", "avi", "png", "jpeg", "y4m", "png-sequence":
+	default:
+		return fmt.Errorf("unsupported -output extension (%s)", filepath.Ext(cfg.OutputFile))
+	}
+	if cfg.At < 0 {
+		return fmt.Errorf("invalid -at (%v)", cfg.At)
+	}
+	if cfg.FirstFrame < 0 {
+		return fmt.Errorf("invalid -first-frame (%d)", cfg.FirstFrame)
+	}
+	if cfg.LastFrame < -1 || (cfg.LastFrame != -1 && cfg.LastFrame < cfg.FirstFrame) {
+		return fmt.Errorf("invalid -last-frame (%d)", cfg.LastFrame)
+	}
+	if cfg.Draft < 0 || cfg.Draft > 1 {
+		return fmt.Errorf("invalid -draft (%f)", cfg.Draft)
+	}
+	if cfg.Draft > 0 && cfg.ContactSheet == "" {
+		return fmt.Errorf("missing -contact-sheet")
+	}
+	if cfg.PaletteFile != "" {
+		if _, err := mandelbrot.PaletteFileFormat(cfg.PaletteFile); err != nil {
+			return fmt.Errorf("invalid -palette: %w", err)
+		}
+	}
+	if cfg.ExportPalette != "" {
+		format, err := mandelbrot.PaletteFileFormat(cfg.ExportPalette)
+		if err != nil {
+			return fmt.Errorf("invalid -export-palette: %w", err)
+		}
+		if format == mandelbrot.PaletteFormatUCL {
+			return fmt.Errorf("invalid -export-palette: palette format (%s) cannot be written", format)
+		}
+	}
+	switch cfg.Dither {
+	case mandelbrot.DitherNone, mandelbrot.DitherFloydSteinberg, mandelbrot.DitherBayer:
+	default:
+		return fmt.Errorf("invalid -dither (%s)", cfg.Dither)
+	}
+	switch cfg.GIFPalette {
+	case mandelbrot.GIFPaletteGlobal, mandelbrot.GIFPaletteAdaptive:
+	default:
+		return fmt.Errorf("invalid -gif-palette (%s)", cfg.GIFPalette)
+	}
+	if cfg.JPEGQuality < 1 || cfg.JPEGQuality > 100 {
+		return fmt.Errorf("invalid -jpeg-quality (%d)", cfg.JPEGQuality)
+	}
 	return nil
 }
+
+// OutputFormat returns the output format, as derived from the output file extension.
+func (cfg Config) OutputFormat() string {
+	if cfg.OutputFile == "-" {
+		return "y4m"
+	}
+	if strings.HasSuffix(cfg.OutputFile, "/") || strings.HasSuffix(cfg.OutputFile, string(filepath.Separator)) {
+		return "png-sequence"
+	}
+	if info, err := os.Stat(cfg.OutputFile); err == nil && info.IsDir() {
+		return "png-sequence"
+	}
+	switch ext := strings.ToLower(filepath.Ext(cfg.OutputFile)); ext {
+	case ".jpg", ".jpeg":
+		return "jpeg"
+	default:
+		return strings.TrimPrefix(ext, ".")
+	}
+}
@@ -1,46 +1,278 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
 	"github.com/PieterD/brot/pkg/mandelbrot"
+	"image"
 	"image/color"
-	"image/gif"
+	"image/jpeg"
+	"image/png"
 	"os"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		subcommands := map[string]func(args []string) error{
+			"palettes": runPalettes,
+			"extract":  runExtract,
+		}
+		if subcommand, ok := subcommands[os.Args[1]]; ok {
+			if err := subcommand(os.Args[2:]); err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "running brot %s: %v\n", os.Args[1], err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
 	cfg, ok := NewConfigFromFlags()
 	if !ok {
 		os.Exit(1)
 	}
 	if err := run(cfg); err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "running brot: %v", err)
+		_, _ = fmt.Fprintf(os.Stderr, "running brot: %v\n", err)
 		os.Exit(1)
 	}
 }
 
 func run(cfg Config) error {
-	const paletteSize = 256
 	animationConfig, err := mandelbrot.NewAnimateConfigFromFile(cfg.ConfigFile)
 	if err != nil {
 		return fmt.Errorf("extracting animation config: %w", err)
 	}
-	blue := color.RGBA{
-		B: 255,
-		A: 255,
+	if cfg.PaletteFile != "" {
+		var paletteConfig mandelbrot.PaletteConfig
+		if animationConfig.Palette != nil {
+			paletteConfig = *animationConfig.Palette
+		}
+		paletteConfig.Stops = nil
+		paletteConfig.File = cfg.PaletteFile
+		paletteConfig.Name = ""
+		paletteConfig.Preset = ""
+		if err := paletteConfig.LoadFile(""); err != nil {
+			return fmt.Errorf("loading -palette: %w", err)
+		}
+		animationConfig.Palette = &paletteConfig
+	}
+	palette, err := animationConfig.BasePalette(mandelbrot.DefaultPalette())
+	if err != nil {
+		return err
+	}
+	// Everything below is given the resolved palette, and uses it instead of building the Palette again.
+	animationConfig.Palette = nil
+	if cfg.ExportPalette != "" {
+		// The last entry is the interior color, which is not part of the gradient.
+		if err := mandelbrot.WritePaletteFile(cfg.ExportPalette, palette[:len(palette)-1]); err != nil {
+			return fmt.Errorf("exporting palette: %w", err)
+		}
+		return nil
+	}
+	if cfg.Draft > 0 {
+		animationConfig, err = animationConfig.Draft(cfg.Draft)
+		if err != nil {
+			return fmt.Errorf("creating draft config: %w", err)
+		}
+		if err := writeContactSheet(cfg.ContactSheet, animationConfig, palette); err != nil {
+			return fmt.Errorf("writing contact sheet: %w", err)
+		}
+	}
+	if cfg.CountFrames {
+		frameCount, err := mandelbrot.FrameCount(animationConfig, palette)
+		if err != nil {
+			return fmt.Errorf("counting frames: %w", err)
+		}
+		fmt.Println(frameCount)
+		return nil
+	}
+	switch cfg.OutputFormat() {
+	case "png", "jpeg":
+		return runStill(cfg, animationConfig, palette)
 	}
-	palette := mandelbrot.Gradient(blue, color.Black, 256)
-	g, err := mandelbrot.Animate(animationConfig, palette)
+	sink, closeOutput, err := newSink(cfg, animationConfig, palette)
 	if err != nil {
+		return fmt.Errorf("creating %s output: %w", cfg.OutputFormat(), err)
+	}
+	defer closeOutput()
+	opts := mandelbrot.AnimateOptions{
+		Range: mandelbrot.FrameRange{
+			First: cfg.FirstFrame,
+			Last:  cfg.LastFrame,
+		},
+	}
+	if cfg.CheckpointDir != "" {
+		checkpoint, err := mandelbrot.OpenCheckpoint(cfg.CheckpointDir)
+		if err != nil {
+			return fmt.Errorf("opening checkpoint: %w", err)
+		}
+		opts.Checkpoint = checkpoint
+	}
+	if err := mandelbrot.AnimateWith(animationConfig, palette, sink, opts); err != nil {
 		return fmt.Errorf("animating: %w", err)
 	}
+	if err := sink.Close(); err != nil {
+		return fmt.Errorf("finishing %s output: %w", cfg.OutputFormat(), err)
+	}
+	return nil
+}
+
+// gifPaletteStills is the number of stills the global palette of dithered GIF output is sampled from.
+const gifPaletteStills = 16
+
+// newSink creates the frame sink for the output format.
+// The returned function closes the output file, if there is one.
+func newSink(cfg Config, animationConfig mandelbrot.AnimationConfig, palette color.Palette) (mandelbrot.FrameSink, func(), error) {
+	if cfg.OutputFormat() == "png-sequence" {
+		sink, err := mandelbrot.NewPNGSequenceSink(cfg.OutputFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return sink, func() {}, nil
+	}
+	out := os.Stdout
+	if cfg.OutputFile != "-" {
+		var err error
+		out, err = os.Create(cfg.OutputFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("creating output file: %w", err)
+		}
+	}
+	closeOutput := func() { _ = out.Close() }
+	switch cfg.OutputFormat() {
+	case "apng":
+		sink := mandelbrot.NewAPNGSink(out, animationConfig.Width, animationConfig.Height)
+		// APNG counts the total number of plays, rather than the number of repeats.
+		switch {
+		case animationConfig.LoopCount < 0:
+			sink.Plays = 1
+		case animationConfig.LoopCount > 0:
+			sink.Plays = animationConfig.LoopCount + 1
+		}
+		return sink, closeOutput, nil
+	case "avi":
+		sink, err := mandelbrot.NewAVISink(out, animationConfig.Width, animationConfig.Height, animationConfig.FPS, cfg.JPEGQuality)
+		if err != nil {
+			closeOutput()
+			return nil, nil, err
+		}
+		return sink, closeOutput, nil
+	case "y4m":
+		return mandelbrot.NewY4MSink(out, animationConfig.Width, animationConfig.Height, animationConfig.FPS), closeOutput, nil
+	default:
+		if cfg.Dither != mandelbrot.DitherNone && cfg.GIFPalette == mandelbrot.GIFPaletteGlobal {
+			// Dithered frames are blended between palette entries, and need a palette covering all of those colors.
+			var err error
+			palette, err = mandelbrot.SampleGIFPalette(animationConfig, palette, gifPaletteStills)
+			if err != nil {
+				closeOutput()
+				return nil, nil, fmt.Errorf("sampling palette: %w", err)
+			}
+		}
+		sink := mandelbrot.NewGIFSink(out, animationConfig.Width, animationConfig.Height, palette)
+		sink.Optimize = cfg.OptimizeGIF
+		sink.LoopCount = animationConfig.LoopCount
+		sink.Dither = cfg.Dither
+		sink.PaletteMode = cfg.GIFPalette
+		return sink, closeOutput, nil
+	}
+}
+
+// runPalettes renders swatches of the built-in palettes, so that one can be chosen as a Preset.
+func runPalettes(args []string) error {
+	flags := flag.NewFlagSet("palettes", flag.ExitOnError)
+	output := flags.String("output", "palettes.png", "Filename of the PNG output file")
+	width := flags.Int("width", 512, "Width of every swatch strip")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	names := flags.Args()
+	if len(names) == 0 {
+		names = mandelbrot.PaletteNames()
+	}
+	img, err := mandelbrot.PaletteSwatches(names, *width)
+	if err != nil {
+		return fmt.Errorf("rendering swatches: %w", err)
+	}
+	out, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("creating output file: %w", err)
+	}
+	defer func() { _ = out.Close() }()
+	if err := png.Encode(out, img); err != nil {
+		return fmt.Errorf("encoding PNG: %w", err)
+	}
+	return nil
+}
+
+// runExtract extracts a palette from a reference image, and prints it as a palette config
+// or writes it to a palette file.
+func runExtract(args []string) error {
+	flags := flag.NewFlagSet("extract", flag.ExitOnError)
+	colors := flags.Int("colors", 8, "Number of colors to extract")
+	method := flags.String("method", mandelbrot.ExtractKMeans, "Extraction method; either median-cut or k-means")
+	output := flags.String("output", "", "Palette file (.map, .gpl, .ugr or .csv) to write; by default the palette config is printed as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected a single PNG or JPEG image, got (%d) arguments", flags.NArg())
+	}
+	f, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("opening image: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return fmt.Errorf("decoding image: %w", err)
+	}
+	palette, err := mandelbrot.ExtractPalette(img, *colors, *method)
+	if err != nil {
+		return fmt.Errorf("extracting palette: %w", err)
+	}
+	if *output != "" {
+		return mandelbrot.WritePaletteFile(*output, palette)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(mandelbrot.PaletteConfigFromColors(palette))
+}
+
+func writeContactSheet(fileName string, animationConfig mandelbrot.AnimationConfig, palette color.Palette) error {
+	img, err := mandelbrot.ContactSheet(animationConfig, palette)
+	if err != nil {
+		return fmt.Errorf("rendering contact sheet: %w", err)
+	}
+	out, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("creating contact sheet file: %w", err)
+	}
+	defer func() { _ = out.Close() }()
+	if err := png.Encode(out, img); err != nil {
+		return fmt.Errorf("encoding PNG: %w", err)
+	}
+	return nil
+}
+
+func runStill(cfg Config, animationConfig mandelbrot.AnimationConfig, palette color.Palette) error {
+	img, err := mandelbrot.RenderStill(animationConfig, palette, cfg.At)
+	if err != nil {
+		return fmt.Errorf("rendering still: %w", err)
+	}
 	out, err := os.Create(cfg.OutputFile)
 	if err != nil {
 		return fmt.Errorf("creating output file: %w", err)
 	}
 	defer func() { _ = out.Close() }()
-	if err := gif.EncodeAll(out, g); err != nil {
-		return fmt.Errorf("encoding GIF: %w", err)
+	switch cfg.OutputFormat() {
+	case "png":
+		if err := png.Encode(out, img); err != nil {
+			return fmt.Errorf("encoding PNG: %w", err)
+		}
+	case "jpeg":
+		if err := jpeg.Encode(out, img, &jpeg.Options{Quality: cfg.JPEGQuality}); err != nil {
+			return fmt.Errorf("encoding JPEG: %w", err)
+		}
 	}
 	return nil
 }
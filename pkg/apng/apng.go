@@ -0,0 +1,314 @@
+// Package apng writes animated PNG files, one frame at a time.
+package apng
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"io"
+	"math"
+	"time"
+)
+
+const (
+	disposeOpNone = 0
+	blendOpSource = 0
+	blendOpOver   = 1
+)
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+// Encoder writes frames to an APNG stream.
+// Every frame after the first is stored as the rectangle that changed compared to the previous frame.
+// Unchanged pixels within that rectangle are made transparent when the frame is opaque,
+// so that they compress well and are blended over the previous frame.
+type Encoder struct {
+	w          *bufio.Writer
+	width      int
+	height     int
+	frameCount int
+	plays      int
+	written    int
+	seq        uint32
+	// canvas contains the output as it should be shown after the last written frame.
+	canvas *image.NRGBA
+}
+
+// NewEncoder creates an encoder for exactly frameCount frames of the given size.
+// The animation is played plays times; 0 plays it forever.
+func NewEncoder(w io.Writer, width, height, frameCount, plays int) *Encoder {
+	return &Encoder{
+		w:          bufio.NewWriter(w),
+		width:      width,
+		height:     height,
+		frameCount: frameCount,
+		plays:      plays,
+	}
+}
+
+// WriteFrame adds a frame that is shown for the given delay.
+func (e *Encoder) WriteFrame(img image.Image, delay time.Duration) error {
+	if e.written >= e.frameCount {
+		return fmt.Errorf("all %d frames have already been written", e.frameCount)
+	}
+	b := img.Bounds()
+	if b.Dx() != e.width || b.Dy() != e.height {
+		return fmt.Errorf("frame size (%d,%d) does not match animation size (%d,%d)", b.Dx(), b.Dy(), e.width, e.height)
+	}
+	frame := image.NewNRGBA(image.Rect(0, 0, e.width, e.height))
+	for y := 0; y < e.height; y++ {
+		for x := 0; x < e.width; x++ {
+			frame.Set(x, y, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	delayNum, delayDen := delayFraction(delay)
+	if e.written == 0 {
+		if err := e.writeHeader(); err != nil {
+			return fmt.Errorf("writing header: %w", err)
+		}
+		if err := e.writeFrameControl(frame.Rect, delayNum, delayDen, blendOpSource); err != nil {
+			return fmt.Errorf("writing frame control: %w", err)
+		}
+		data, err := compress(frame)
+		if err != nil {
+			return fmt.Errorf("compressing: %w", err)
+		}
+		if err := e.writeChunk("IDAT", data); err != nil {
+			return fmt.Errorf("writing image data: %w", err)
+		}
+		e.canvas = frame
+		e.written++
+		return nil
+	}
+	rect, blendOp, patch := e.difference(frame)
+	if err := e.writeFrameControl(rect, delayNum, delayDen, blendOp); err != nil {
+		return fmt.Errorf("writing frame control: %w", err)
+	}
+	data, err := compress(patch)
+	if err != nil {
+		return fmt.Errorf("compressing: %w", err)
+	}
+	seq := make([]byte, 4)
+	binary.BigEndian.PutUint32(seq, e.nextSeq())
+	if err := e.writeChunk("fdAT", append(seq, data...)); err != nil {
+		return fmt.Errorf("writing frame data: %w", err)
+	}
+	e.canvas = frame
+	e.written++
+	return nil
+}
+
+// Close finishes the stream. It fails if fewer frames were written than announced.
+func (e *Encoder) Close() error {
+	if e.written != e.frameCount {
+		return fmt.Errorf("wrote %d frames, expected %d", e.written, e.frameCount)
+	}
+	if err := e.writeChunk("IEND", nil); err != nil {
+		return fmt.Errorf("writing end: %w", err)
+	}
+	return e.w.Flush()
+}
+
+// difference returns the rectangle in which frame differs from the canvas,
+// the blend operation to use, and the image to store for that rectangle.
+func (e *Encoder) difference(frame *image.NRGBA) (image.Rectangle, byte, *image.NRGBA) {
+	changed := image.Rectangle{}
+	opaque := true
+	for y := 0; y < e.height; y++ {
+		for x := 0; x < e.width; x++ {
+			i := frame.PixOffset(x, y)
+			if frame.Pix[i+3] != 0xff {
+				opaque = false
+			}
+			if !bytes.Equal(frame.Pix[i:i+4], e.canvas.Pix[i:i+4]) {
+				changed = changed.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	if changed.Empty() {
+		// Nothing changed; blend a single transparent pixel over the canvas.
+		return image.Rect(0, 0, 1, 1), blendOpOver, image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	}
+	patch := image.NewNRGBA(image.Rect(0, 0, changed.Dx(), changed.Dy()))
+	for y := changed.Min.Y; y < changed.Max.Y; y++ {
+		for x := changed.Min.X; x < changed.Max.X; x++ {
+			i := frame.PixOffset(x, y)
+			if opaque && bytes.Equal(frame.Pix[i:i+4], e.canvas.Pix[i:i+4]) {
+				continue
+			}
+			j := patch.PixOffset(x-changed.Min.X, y-changed.Min.Y)
+			copy(patch.Pix[j:j+4], frame.Pix[i:i+4])
+		}
+	}
+	if !opaque {
+		return changed, blendOpSource, patch
+	}
+	return changed, blendOpOver, patch
+}
+
+func (e *Encoder) writeHeader() error {
+	if _, err := e.w.Write(pngSignature); err != nil {
+		return err
+	}
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:4], uint32(e.width))
+	binary.BigEndian.PutUint32(ihdr[4:8], uint32(e.height))
+	ihdr[8] = 8  // Bit depth.
+	ihdr[9] = 6  // Color type: RGBA.
+	ihdr[10] = 0 // Compression method.
+	ihdr[11] = 0 // Filter method.
+	ihdr[12] = 0 // Interlace method.
+	if err := e.writeChunk("IHDR", ihdr); err != nil {
+		return err
+	}
+	actl := make([]byte, 8)
+	binary.BigEndian.PutUint32(actl[0:4], uint32(e.frameCount))
+	binary.BigEndian.PutUint32(actl[4:8], uint32(e.plays))
+	return e.writeChunk("acTL", actl)
+}
+
+func (e *Encoder) writeFrameControl(rect image.Rectangle, delayNum, delayDen uint16, blendOp byte) error {
+	fctl := make([]byte, 26)
+	binary.BigEndian.PutUint32(fctl[0:4], e.nextSeq())
+	binary.BigEndian.PutUint32(fctl[4:8], uint32(rect.Dx()))
+	binary.BigEndian.PutUint32(fctl[8:12], uint32(rect.Dy()))
+	binary.BigEndian.PutUint32(fctl[12:16], uint32(rect.Min.X))
+	binary.BigEndian.PutUint32(fctl[16:20], uint32(rect.Min.Y))
+	binary.BigEndian.PutUint16(fctl[20:22], delayNum)
+	binary.BigEndian.PutUint16(fctl[22:24], delayDen)
+	fctl[24] = disposeOpNone
+	fctl[25] = blendOp
+	return e.writeChunk("fcTL", fctl)
+}
+
+func (e *Encoder) nextSeq() uint32 {
+	seq := e.seq
+	e.seq++
+	return seq
+}
+
+func (e *Encoder) writeChunk(chunkType string, data []byte) error {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(data)))
+	copy(header[4:8], chunkType)
+	crc := crc32.NewIEEE()
+	_, _ = crc.Write(header[4:8])
+	_, _ = crc.Write(data)
+	footer := make([]byte, 4)
+	binary.BigEndian.PutUint32(footer, crc.Sum32())
+	for _, part := range [][]byte{header, data, footer} {
+		if _, err := e.w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compress filters and deflates the image, choosing the filter for each row
+// with the smallest sum of absolute differences, as libpng does.
+func compress(img *image.NRGBA) ([]byte, error) {
+	const bpp = 4
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	rowLen := img.Rect.Dx() * bpp
+	prev := make([]byte, rowLen)
+	candidates := make([][]byte, 5)
+	for i := range candidates {
+		candidates[i] = make([]byte, rowLen+1)
+		candidates[i][0] = byte(i)
+	}
+	for y := 0; y < img.Rect.Dy(); y++ {
+		row := img.Pix[y*img.Stride : y*img.Stride+rowLen]
+		for i := 0; i < rowLen; i++ {
+			var left, upLeft byte
+			if i >= bpp {
+				left = row[i-bpp]
+				upLeft = prev[i-bpp]
+			}
+			up := prev[i]
+			candidates[0][i+1] = row[i]
+			candidates[1][i+1] = row[i] - left
+			candidates[2][i+1] = row[i] - up
+			candidates[3][i+1] = row[i] - byte((int(left)+int(up))/2)
+			candidates[4][i+1] = row[i] - paeth(left, up, upLeft)
+		}
+		best, bestSum := 0, math.MaxInt
+		for i, candidate := range candidates {
+			sum := 0
+			for _, v := range candidate[1:] {
+				sum += int(math.Abs(float64(int8(v))))
+			}
+			if sum < bestSum {
+				best, bestSum = i, sum
+			}
+		}
+		if _, err := zw.Write(candidates[best]); err != nil {
+			return nil, err
+		}
+		copy(prev, row)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa := abs(p - int(a))
+	pb := abs(p - int(b))
+	pc := abs(p - int(c))
+	if pa <= pb && pa <= pc {
+		return a
+	}
+	if pb <= pc {
+		return b
+	}
+	return c
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// delayFraction expresses the delay as a fraction of seconds, with both parts fitting in 16 bits.
+// It uses continued fractions to find the closest approximation, so that for example
+// a delay of a thirtieth of a second is stored exactly, rather than rounded to milliseconds.
+func delayFraction(delay time.Duration) (uint16, uint16) {
+	seconds := delay.Seconds()
+	if seconds <= 0 {
+		return 0, 1
+	}
+	if seconds >= math.MaxUint16 {
+		return math.MaxUint16, 1
+	}
+	// Convergents h/k of the continued fraction expansion.
+	h0, h1 := 0.0, 1.0
+	k0, k1 := 1.0, 0.0
+	x := seconds
+	for i := 0; i < 32; i++ {
+		a := math.Floor(x)
+		h2 := a*h1 + h0
+		k2 := a*k1 + k0
+		if h2 > math.MaxUint16 || k2 > math.MaxUint16 {
+			break
+		}
+		h0, h1 = h1, h2
+		k0, k1 = k1, k2
+		if math.Abs(h1/k1-seconds) < 1e-9 || x == a {
+			break
+		}
+		x = 1 / (x - a)
+	}
+	if k1 == 0 {
+		return math.MaxUint16, 1
+	}
+	return uint16(h1), uint16(k1)
+}
@@ -0,0 +1,139 @@
+package apng_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/PieterD/brot/pkg/apng"
+	"github.com/stretchr/testify/require"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"testing"
+	"time"
+)
+
+type chunk struct {
+	kind string
+	data []byte
+}
+
+func readChunks(t *testing.T, b []byte) []chunk {
+	require.True(t, bytes.HasPrefix(b, []byte("\x89PNG\r\n\x1a\n")))
+	b = b[8:]
+	var chunks []chunk
+	for len(b) > 0 {
+		length := binary.BigEndian.Uint32(b[0:4])
+		kind := string(b[4:8])
+		data := b[8 : 8+length]
+		crc := binary.BigEndian.Uint32(b[8+length : 12+length])
+		require.Equal(t, crc32.ChecksumIEEE(b[4:8+length]), crc, "crc of %s", kind)
+		chunks = append(chunks, chunk{kind: kind, data: data})
+		b = b[12+length:]
+	}
+	return chunks
+}
+
+// decodeFrames reconstructs every frame by wrapping the frame data in a standalone PNG,
+// and composing it onto the canvas as instructed by the frame control chunk.
+func decodeFrames(t *testing.T, chunks []chunk) ([]*image.NRGBA, []time.Duration) {
+	var ihdr []byte
+	var frames []*image.NRGBA
+	var delays []time.Duration
+	var canvas *image.NRGBA
+	var fctl []byte
+	for _, c := range chunks {
+		switch c.kind {
+		case "IHDR":
+			ihdr = c.data
+			canvas = image.NewNRGBA(image.Rect(0, 0, int(binary.BigEndian.Uint32(ihdr[0:4])), int(binary.BigEndian.Uint32(ihdr[4:8]))))
+		case "fcTL":
+			fctl = c.data
+		case "IDAT", "fdAT":
+			data := c.data
+			if c.kind == "fdAT" {
+				data = data[4:]
+			}
+			w := binary.BigEndian.Uint32(fctl[4:8])
+			h := binary.BigEndian.Uint32(fctl[8:12])
+			x := int(binary.BigEndian.Uint32(fctl[12:16]))
+			y := int(binary.BigEndian.Uint32(fctl[16:20]))
+			num := binary.BigEndian.Uint16(fctl[20:22])
+			den := binary.BigEndian.Uint16(fctl[22:24])
+			frameHeader := append([]byte{}, ihdr...)
+			binary.BigEndian.PutUint32(frameHeader[0:4], w)
+			binary.BigEndian.PutUint32(frameHeader[4:8], h)
+			var standalone bytes.Buffer
+			standalone.Write([]byte("\x89PNG\r\n\x1a\n"))
+			for _, part := range []chunk{{"IHDR", frameHeader}, {"IDAT", data}, {"IEND", nil}} {
+				writeChunk(&standalone, part)
+			}
+			patch, err := png.Decode(&standalone)
+			require.NoError(t, err)
+			op := draw.Src
+			if fctl[25] == 1 {
+				op = draw.Over
+			}
+			draw.Draw(canvas, image.Rect(x, y, x+int(w), y+int(h)), patch, image.Point{}, op)
+			frame := image.NewNRGBA(canvas.Rect)
+			copy(frame.Pix, canvas.Pix)
+			frames = append(frames, frame)
+			delays = append(delays, time.Duration(num)*time.Second/time.Duration(den))
+		}
+	}
+	return frames, delays
+}
+
+func writeChunk(buf *bytes.Buffer, c chunk) {
+	_ = binary.Write(buf, binary.BigEndian, uint32(len(c.data)))
+	buf.WriteString(c.kind)
+	buf.Write(c.data)
+	_ = binary.Write(buf, binary.BigEndian, crc32.ChecksumIEEE(append([]byte(c.kind), c.data...)))
+}
+
+func TestEncoder(t *testing.T) {
+	const width, height = 9, 6
+	var frames []*image.NRGBA
+	for i := 0; i < 4; i++ {
+		img := image.NewNRGBA(image.Rect(0, 0, width, height))
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				img.Set(x, y, color.NRGBA{R: uint8(10 * x), G: uint8(10 * y), B: 100, A: 255})
+			}
+		}
+		if i >= 1 {
+			img.Set(2, 3, color.NRGBA{R: 255, A: 255})
+		}
+		if i >= 3 {
+			img.Set(7, 1, color.NRGBA{G: 255, A: 255})
+			img.Set(4, 4, color.NRGBA{B: 255, A: 255})
+		}
+		frames = append(frames, img)
+	}
+	var buf bytes.Buffer
+	enc := apng.NewEncoder(&buf, width, height, len(frames), 0)
+	for _, frame := range frames {
+		require.NoError(t, enc.WriteFrame(frame, time.Second/30))
+	}
+	require.NoError(t, enc.Close())
+
+	first, err := png.Decode(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, frames[0].Pix, first.(*image.NRGBA).Pix)
+
+	chunks := readChunks(t, buf.Bytes())
+	got, delays := decodeFrames(t, chunks)
+	require.Len(t, got, len(frames))
+	for i := range frames {
+		require.Equal(t, frames[i].Pix, got[i].Pix, "frame %d", i)
+		require.Equal(t, time.Second/30, delays[i])
+	}
+}
+
+func TestEncoderFrameCount(t *testing.T) {
+	var buf bytes.Buffer
+	enc := apng.NewEncoder(&buf, 1, 1, 2, 0)
+	require.NoError(t, enc.WriteFrame(image.NewNRGBA(image.Rect(0, 0, 1, 1)), time.Second))
+	require.Error(t, enc.Close())
+}
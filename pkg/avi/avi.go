@@ -0,0 +1,233 @@
+// Package avi writes Motion-JPEG video in an AVI (RIFF) container.
+package avi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+)
+
+const (
+	avifHasIndex   = 0x10
+	aviifKeyframe  = 0x10
+	mainHeaderSize = 56
+	// Offsets of fields that are only known once all frames have been written.
+	riffSizeOffset     = 4
+	totalFramesOffset  = 48
+	streamLengthOffset = 140
+	moviSizeOffset     = 216
+	moviStart          = 220
+)
+
+type indexEntry struct {
+	offset uint32
+	size   uint32
+}
+
+// Writer writes JPEG encoded frames to an AVI file.
+// Because the file sizes are only known at the end, the underlying writer must support seeking.
+type Writer struct {
+	ws      io.WriteSeeker
+	w       *bufio.Writer
+	width   int
+	height  int
+	quality int
+	// pos is the current position in the file.
+	pos   uint32
+	index []indexEntry
+	// frame holds the last encoded frame, padded to an even size.
+	frame     bytes.Buffer
+	frameSize uint32
+}
+
+// NewWriter writes the AVI headers. Frames are shown at fps frames per second,
+// and compressed with the given JPEG quality (1 to 100).
+func NewWriter(ws io.WriteSeeker, width, height, fps, quality int) (*Writer, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid dimensions (%d,%d)", width, height)
+	}
+	if fps <= 0 {
+		return nil, fmt.Errorf("invalid fps (%d)", fps)
+	}
+	if quality < 1 || quality > 100 {
+		return nil, fmt.Errorf("invalid quality (%d)", quality)
+	}
+	w := &Writer{
+		ws:      ws,
+		w:       bufio.NewWriter(ws),
+		width:   width,
+		height:  height,
+		quality: quality,
+	}
+	if err := w.writeHeaders(fps); err != nil {
+		return nil, fmt.Errorf("writing headers: %w", err)
+	}
+	return w, nil
+}
+
+func (w *Writer) writeHeaders(fps int) error {
+	var b bytes.Buffer
+	le := func(vs ...interface{}) {
+		for _, v := range vs {
+			_ = binary.Write(&b, binary.LittleEndian, v)
+		}
+	}
+	b.WriteString("RIFF")
+	le(uint32(0)) // Patched on Close.
+	b.WriteString("AVI ")
+
+	b.WriteString("LIST")
+	le(uint32(4 + 8 + mainHeaderSize + 8 + 4 + 8 + 56 + 8 + 40))
+	b.WriteString("hdrl")
+
+	b.WriteString("avih")
+	le(uint32(mainHeaderSize))
+	le(
+		uint32(1000000/fps),        // Microseconds per frame.
+		uint32(0),                  // Max bytes per second.
+		uint32(0),                  // Padding granularity.
+		uint32(avifHasIndex),       // Flags.
+		uint32(0),                  // Total frames; patched on Close.
+		uint32(0),                  // Initial frames.
+		uint32(1),                  // Streams.
+		uint32(w.width*w.height*3), // Suggested buffer size.
+		uint32(w.width),            // Width.
+		uint32(w.height),           // Height.
+		[4]uint32{},                // Reserved.
+	)
+
+	b.WriteString("LIST")
+	le(uint32(4 + 8 + 56 + 8 + 40))
+	b.WriteString("strl")
+
+	b.WriteString("strh")
+	le(uint32(56))
+	b.WriteString("vids")
+	b.WriteString("MJPG")
+	le(
+		uint32(0),                  // Flags.
+		uint16(0),                  // Priority.
+		uint16(0),                  // Language.
+		uint32(0),                  // Initial frames.
+		uint32(1),                  // Scale.
+		uint32(fps),                // Rate; rate divided by scale is the frame rate.
+		uint32(0),                  // Start.
+		uint32(0),                  // Length; patched on Close.
+		uint32(w.width*w.height*3), // Suggested buffer size.
+		int32(-1),                  // Quality; -1 is the default.
+		uint32(0),                  // Sample size; 0 for variable size frames.
+		[4]int16{0, 0, int16(w.width), int16(w.height)},
+	)
+
+	b.WriteString("strf")
+	le(uint32(40))
+	le(
+		uint32(40),      // Header size.
+		int32(w.width),  // Width.
+		int32(w.height), // Height.
+		uint16(1),       // Planes.
+		uint16(24),      // Bit count.
+	)
+	b.WriteString("MJPG")
+	le(
+		uint32(w.width*w.height*3), // Image size.
+		int32(0),                   // Horizontal pixels per meter.
+		int32(0),                   // Vertical pixels per meter.
+		uint32(0),                  // Colors used.
+		uint32(0),                  // Important colors.
+	)
+
+	b.WriteString("LIST")
+	le(uint32(0)) // Patched on Close.
+	b.WriteString("movi")
+	if b.Len() != moviStart+4 {
+		return fmt.Errorf("header size (%d) mismatch", b.Len())
+	}
+	return w.write(b.Bytes())
+}
+
+// WriteFrame encodes the image as JPEG and adds it as the next frame.
+func (w *Writer) WriteFrame(img image.Image) error {
+	if img.Bounds().Dx() != w.width || img.Bounds().Dy() != w.height {
+		return fmt.Errorf("frame size (%d,%d) does not match video size (%d,%d)", img.Bounds().Dx(), img.Bounds().Dy(), w.width, w.height)
+	}
+	w.frame.Reset()
+	if err := jpeg.Encode(&w.frame, img, &jpeg.Options{Quality: w.quality}); err != nil {
+		return fmt.Errorf("encoding JPEG: %w", err)
+	}
+	w.frameSize = uint32(w.frame.Len())
+	if w.frameSize%2 == 1 {
+		// Chunks are padded to an even size.
+		w.frame.WriteByte(0)
+	}
+	return w.writeFrame()
+}
+
+// RepeatFrame adds the last frame again, without encoding it again.
+func (w *Writer) RepeatFrame() error {
+	if len(w.index) == 0 {
+		return fmt.Errorf("no frame to repeat")
+	}
+	return w.writeFrame()
+}
+
+func (w *Writer) writeFrame() error {
+	w.index = append(w.index, indexEntry{
+		offset: w.pos - moviStart,
+		size:   w.frameSize,
+	})
+	header := make([]byte, 8)
+	copy(header, "00dc")
+	binary.LittleEndian.PutUint32(header[4:], w.frameSize)
+	if err := w.write(header); err != nil {
+		return err
+	}
+	return w.write(w.frame.Bytes())
+}
+
+// Close writes the index and fills in the sizes in the headers.
+func (w *Writer) Close() error {
+	moviEnd := w.pos
+	var b bytes.Buffer
+	b.WriteString("idx1")
+	_ = binary.Write(&b, binary.LittleEndian, uint32(16*len(w.index)))
+	for _, entry := range w.index {
+		b.WriteString("00dc")
+		_ = binary.Write(&b, binary.LittleEndian, []uint32{aviifKeyframe, entry.offset, entry.size})
+	}
+	if err := w.write(b.Bytes()); err != nil {
+		return fmt.Errorf("writing index: %w", err)
+	}
+	if err := w.w.Flush(); err != nil {
+		return err
+	}
+	patches := []struct {
+		offset int64
+		value  uint32
+	}{
+		{riffSizeOffset, w.pos - 8},
+		{totalFramesOffset, uint32(len(w.index))},
+		{streamLengthOffset, uint32(len(w.index))},
+		{moviSizeOffset, moviEnd - moviStart},
+	}
+	for _, patch := range patches {
+		if _, err := w.ws.Seek(patch.offset, io.SeekStart); err != nil {
+			return fmt.Errorf("seeking to header: %w", err)
+		}
+		if err := binary.Write(w.ws, binary.LittleEndian, patch.value); err != nil {
+			return fmt.Errorf("patching header: %w", err)
+		}
+	}
+	_, err := w.ws.Seek(int64(w.pos), io.SeekStart)
+	return err
+}
+
+func (w *Writer) write(b []byte) error {
+	n, err := w.w.Write(b)
+	w.pos += uint32(n)
+	return err
+}
@@ -0,0 +1,58 @@
+package avi_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/PieterD/brot/pkg/avi"
+	"github.com/stretchr/testify/require"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	const width, height, frames = 16, 8, 3
+	filePath := filepath.Join(t.TempDir(), "test.avi")
+	f, err := os.Create(filePath)
+	require.NoError(t, err)
+	w, err := avi.NewWriter(f, width, height, 25, 90)
+	require.NoError(t, err)
+	for i := 0; i < frames; i++ {
+		img := image.NewRGBA(image.Rect(0, 0, width, height))
+		for j := range img.Pix {
+			img.Pix[j] = uint8(40 * i)
+		}
+		img.Set(0, 0, color.RGBA{R: 255, A: 255})
+		require.NoError(t, w.WriteFrame(img))
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+
+	b, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	le := binary.LittleEndian
+	require.Equal(t, "RIFF", string(b[0:4]))
+	require.Equal(t, uint32(len(b)-8), le.Uint32(b[4:8]))
+	require.Equal(t, "AVI ", string(b[8:12]))
+	require.Equal(t, uint32(frames), le.Uint32(b[48:52]))
+	require.Equal(t, uint32(frames), le.Uint32(b[140:144]))
+	require.Equal(t, "movi", string(b[220:224]))
+	moviEnd := 220 + int(le.Uint32(b[216:220]))
+	require.Equal(t, "idx1", string(b[moviEnd:moviEnd+4]))
+	index := b[moviEnd+8:]
+	require.Len(t, index, 16*frames)
+	for i := 0; i < frames; i++ {
+		entry := index[16*i : 16*(i+1)]
+		require.Equal(t, "00dc", string(entry[0:4]))
+		offset := 220 + int(le.Uint32(entry[8:12]))
+		size := int(le.Uint32(entry[12:16]))
+		require.Equal(t, "00dc", string(b[offset:offset+4]))
+		require.Equal(t, uint32(size), le.Uint32(b[offset+4:offset+8]))
+		img, err := jpeg.Decode(bytes.NewReader(b[offset+8 : offset+8+size]))
+		require.NoError(t, err)
+		require.Equal(t, image.Rect(0, 0, width, height), img.Bounds())
+	}
+}
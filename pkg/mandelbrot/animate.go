@@ -5,25 +5,85 @@ import (
 	"fmt"
 	"image"
 	"image/color"
-	"image/gif"
+	"image/draw"
+	"math"
 	"os"
+	"path/filepath"
 	"time"
 )
 
+const (
+	LoopModeForward  = "forward"
+	LoopModePingPong = "ping-pong"
+)
+
 type AnimationConfig struct {
 	Width         int
 	Height        int
 	FPS           int
 	MaxIterations int
+	// IterationMode is one of "fixed" (the default), "auto" or "adaptive".
+	// In the auto and adaptive modes, MaxIterations is the iteration count at a zoom of 1.
+	IterationMode string
+	// MaxIterationsLimit caps the iteration count in the auto and adaptive modes.
+	// It defaults to 16 times MaxIterations.
+	MaxIterationsLimit int
+	// MotionBlurSamples is the number of sub-frames averaged into every frame; 0 or 1 disables motion blur.
+	MotionBlurSamples int
+	// ShutterAngle is the part of the frame duration, in degrees, over which sub-frames are spread.
+	// It defaults to 180; 360 blurs each frame into the next.
+	ShutterAngle float64
+	// LoopMode is either "forward" (the default) or "ping-pong",
+	// which plays the animation forward and then back, reusing the rendered frames.
+	LoopMode string
+	// LoopCount is the number of times the animation repeats after playing once; 0 repeats forever,
+	// and -1 plays it only once, ending with an extra frame on the last keyframe.
+	LoopCount int
+	// SeamlessLoop checks that the animation ends where it starts, so that it loops without a jump.
+	SeamlessLoop bool
+	// CycleSpeed rotates the palette by this many entries per second; negative values rotate backwards.
+	// When the Path consists of a single element, the animation lasts exactly one full rotation.
+	CycleSpeed float64
+	// Interpolation selects how the camera moves between keyframes;
+	// either "linear" (the default) or "catmull-rom".
+	Interpolation string
 	Path          []AnimationConfigPathElement
+	// Overlays are drawn onto every frame, in order.
+	Overlays []Overlay
+	// Palette, when set, replaces the palette passed to Animate.
+	Palette *PaletteConfig
+	// Mapping controls how escape values are mapped onto the palette.
+	Mapping ColorMapping
 }
 
 type AnimationConfigPathElement struct {
-	Zoom        float64
-	TargetX     float64
-	TargetY     float64
-	RawDuration string        `json:"Duration"`
-	Duration    time.Duration `json:"-"`
+	Zoom    float64
+	TargetX float64
+	TargetY float64
+	// MaxIterations overrides the animation's MaxIterations at this keyframe.
+	// It is interpolated between keyframes.
+	MaxIterations int
+	RawDuration   string        `json:"Duration"`
+	Duration      time.Duration `json:"-"`
+	RawEasing     string        `json:"Easing"`
+	Easing        Easing        `json:"-"`
+	RawHold       string        `json:"Hold"`
+	// Hold keeps the camera still at this element for the given duration, after arriving at it.
+	Hold time.Duration `json:"-"`
+	// Transition overrides how the camera moves from the previous element to this one.
+	// The only supported value is "smooth-zoom"; when empty, the animation's Interpolation is used.
+	Transition string
+	// Palette changes the palette at this element, blending from the previous palette on the way here.
+	// Later elements keep this palette until they change it again.
+	Palette *PaletteConfig
+	// Mapping overrides the fields it sets of the Mapping of the element before it, or of the animation's Mapping;
+	// it is interpolated between elements, and kept by later elements until they change it again.
+	// A field that is present in the JSON is set, even to zero, which returns it to its default.
+	Mapping *ColorMapping
+	// palette is the palette at this element, and paletteSpace the color space in which to blend towards it;
+	// both are filled in by keyframes.
+	palette      color.Palette
+	paletteSpace string
 }
 
 func (cfg AnimationConfig) Validate() error {
@@ -42,11 +102,69 @@ func (cfg AnimationConfig) Validate() error {
 	if len(cfg.Path) == 0 {
 		return fmt.Errorf("at least one Path element is required")
 	}
+	switch cfg.Interpolation {
+	case "", PathInterpolationLinear, PathInterpolationCatmullRom:
+	default:
+		return fmt.Errorf("invalid Interpolation (%s)", cfg.Interpolation)
+	}
+	switch cfg.IterationMode {
+	case "", IterationModeFixed, IterationModeAuto, IterationModeAdaptive:
+	default:
+		return fmt.Errorf("invalid IterationMode (%s)", cfg.IterationMode)
+	}
+	switch cfg.LoopMode {
+	case "", LoopModeForward, LoopModePingPong:
+	default:
+		return fmt.Errorf("invalid LoopMode (%s)", cfg.LoopMode)
+	}
+	if cfg.LoopCount < -1 {
+		return fmt.Errorf("invalid LoopCount (%d)", cfg.LoopCount)
+	}
+	if cfg.MotionBlurSamples < 0 {
+		return fmt.Errorf("invalid MotionBlurSamples (%d)", cfg.MotionBlurSamples)
+	}
+	if cfg.ShutterAngle < 0 || cfg.ShutterAngle > 360 {
+		return fmt.Errorf("invalid ShutterAngle (%f)", cfg.ShutterAngle)
+	}
+	if cfg.MaxIterationsLimit < 0 {
+		return fmt.Errorf("invalid MaxIterationsLimit (%d)", cfg.MaxIterationsLimit)
+	}
 	for i, pe := range cfg.Path {
 		if err := pe.Validate(i == 0); err != nil {
 			return fmt.Errorf("path element (%d): %w", i, err)
 		}
 	}
+	if cfg.Palette != nil {
+		if err := cfg.Palette.Validate(); err != nil {
+			return fmt.Errorf("palette: %w", err)
+		}
+	}
+	if err := cfg.Mapping.Validate(); err != nil {
+		return fmt.Errorf("mapping: %w", err)
+	}
+	if err := cfg.validateInterior(); err != nil {
+		return err
+	}
+	for i, o := range cfg.Overlays {
+		if err := o.Validate(); err != nil {
+			return fmt.Errorf("overlay (%d): %w", i, err)
+		}
+	}
+	return nil
+}
+
+// validateInterior rejects Interior colors set in both a palette and a mapping,
+// as the mapping's would replace the palette's without it being apparent from the config.
+func (cfg AnimationConfig) validateInterior() error {
+	inPalette := cfg.Palette != nil && cfg.Palette.Interior != ""
+	inMapping := cfg.Mapping.Interior != ""
+	for _, pe := range cfg.Path {
+		inPalette = inPalette || pe.Palette != nil && pe.Palette.Interior != ""
+		inMapping = inMapping || pe.Mapping != nil && pe.Mapping.Interior != ""
+	}
+	if inPalette && inMapping {
+		return fmt.Errorf("an Interior color can be set in palettes or in mappings, but not in both")
+	}
 	return nil
 }
 
@@ -65,6 +183,32 @@ func (cfg AnimationConfigPathElement) Validate(first bool) error {
 	if cfg.Zoom < 1 {
 		return fmt.Errorf("zoom (%f) cannot be below 1", cfg.Zoom)
 	}
+	if cfg.MaxIterations < 0 {
+		return fmt.Errorf("invalid MaxIterations (%d)", cfg.MaxIterations)
+	}
+	if cfg.Hold < 0 {
+		return fmt.Errorf("invalid Hold (%v)", cfg.Hold)
+	}
+	if first && cfg.RawEasing != "" {
+		return fmt.Errorf("first Path element cannot have an Easing")
+	}
+	switch {
+	case cfg.Transition == "":
+	case first:
+		return fmt.Errorf("first Path element cannot have a Transition")
+	case cfg.Transition != TransitionSmoothZoom:
+		return fmt.Errorf("invalid Transition (%s)", cfg.Transition)
+	}
+	if cfg.Palette != nil {
+		if err := cfg.Palette.Validate(); err != nil {
+			return fmt.Errorf("palette: %w", err)
+		}
+	}
+	if cfg.Mapping != nil {
+		if err := cfg.Mapping.Validate(); err != nil {
+			return fmt.Errorf("mapping: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -80,8 +224,31 @@ func NewAnimateConfigFromFile(filePath string) (AnimationConfig, error) {
 	if err := dec.Decode(&v); err != nil {
 		return AnimationConfig{}, fmt.Errorf("decoding JSON: %w", err)
 	}
+	dir := filepath.Dir(filePath)
+	if v.Palette != nil {
+		if err := v.Palette.LoadFile(dir); err != nil {
+			return AnimationConfig{}, fmt.Errorf("loading palette: %w", err)
+		}
+	}
 	for i := range v.Path {
 		pathElement := &v.Path[i]
+		if pathElement.Palette != nil {
+			if err := pathElement.Palette.LoadFile(dir); err != nil {
+				return AnimationConfig{}, fmt.Errorf("loading palette (path element %d): %w", i, err)
+			}
+		}
+		easing, err := ParseEasing(pathElement.RawEasing)
+		if err != nil {
+			return AnimationConfig{}, fmt.Errorf("invalid easing (%s): %w", pathElement.RawEasing, err)
+		}
+		pathElement.Easing = easing
+		if pathElement.RawHold != "" {
+			hold, err := time.ParseDuration(pathElement.RawHold)
+			if err != nil {
+				return AnimationConfig{}, fmt.Errorf("invalid hold (%s): %w", pathElement.RawHold, err)
+			}
+			pathElement.Hold = hold
+		}
 		if pathElement.RawDuration == "" {
 			continue
 		}
@@ -94,73 +261,452 @@ func NewAnimateConfigFromFile(filePath string) (AnimationConfig, error) {
 	return v, nil
 }
 
-func Animate(cfg AnimationConfig, palette color.Palette) (*gif.GIF, error) {
-	g := &gif.GIF{
-		Image:     nil,
-		Delay:     nil,
-		LoopCount: 0,
-		Disposal:  nil,
-		Config: image.Config{
-			ColorModel: palette,
-			Width:      cfg.Width,
-			Height:     cfg.Height,
-		},
-		BackgroundIndex: 0,
-	}
-	if len(cfg.Path) < 1 {
-		return nil, fmt.Errorf("at least one path elements are required")
-	}
-	if len(cfg.Path) == 1 {
-		renderCfg := RenderConfig{
-			MaxIterations: cfg.MaxIterations,
-			Zoom:          cfg.Path[0].Zoom,
-			TargetX:       cfg.Path[0].TargetX,
-			TargetY:       cfg.Path[0].TargetY,
-		}
-		imgRect := image.Rect(0, 0, cfg.Width, cfg.Height)
-		img := image.NewPaletted(imgRect, palette)
-		if err := Render(renderCfg, img, palette); err != nil {
-			return nil, fmt.Errorf("rendering single frame: %w", err)
-		}
-
-	}
-	for i := 1; i < len(cfg.Path); i++ {
-		from := cfg.Path[i-1]
-		to := cfg.Path[i]
-		if err := animatePathLink(cfg, i == 1, palette, g, from, to); err != nil {
-			return nil, fmt.Errorf("animating path link (%d): %w", i, err)
-		}
-	}
-	return g, nil
-}
-
-func animatePathLink(cfg AnimationConfig, includeFirst bool, palette color.Palette, g *gif.GIF, from, to AnimationConfigPathElement) error {
-	fmt.Printf("link: from %+v to %+v\n", from, to)
-	frameDuration := time.Second / time.Duration(cfg.FPS)
-	frameDelay := int(frameDuration.Seconds() * 100)
-	frameCount := int(to.Duration.Seconds() * float64(cfg.FPS))
-	zoomInterp := NewInterpolator(from.Zoom, to.Zoom, frameCount)
-	xInterp := NewInterpolator(from.TargetX, to.TargetX, frameCount)
-	yInterp := NewInterpolator(from.TargetY, to.TargetY, frameCount)
-	currentFrame := 0
-	if !includeFirst {
-		currentFrame++
-	}
-	for ; currentFrame < frameCount; currentFrame++ {
-		fmt.Printf("rendering %d/%d\n", currentFrame+1, frameCount)
-		imgRect := image.Rect(0, 0, cfg.Width, cfg.Height)
-		img := image.NewPaletted(imgRect, palette)
-		renderCfg := RenderConfig{
-			MaxIterations: cfg.MaxIterations,
-			Zoom:          zoomInterp.At(currentFrame),
-			TargetX:       xInterp.At(currentFrame),
-			TargetY:       yInterp.At(currentFrame),
-		}
-		if err := Render(renderCfg, img, palette); err != nil {
-			return fmt.Errorf("rendering (frame %d/%d): %w", currentFrame, frameCount, err)
-		}
-		g.Image = append(g.Image, img)
-		g.Delay = append(g.Delay, frameDelay)
+// Animate renders every frame of the animation, and passes them to the sink as they are done.
+// The sink is not closed.
+func Animate(cfg AnimationConfig, palette color.Palette, sink FrameSink) error {
+	return AnimateRange(cfg, palette, sink, FrameRange{First: 0, Last: -1})
+}
+
+// FrameRange selects frames by their index in the full animation.
+// Both ends are inclusive; a Last of -1 selects up to the end of the animation.
+type FrameRange struct {
+	First int
+	Last  int
+}
+
+// AnimateRange renders only the frames within the range, so that the work on a long animation can be split.
+// Frames keep their index in the full animation, regardless of the range.
+// In ping-pong mode, the frames that are played back in reverse are kept in memory until they are needed.
+func AnimateRange(cfg AnimationConfig, palette color.Palette, sink FrameSink, frameRange FrameRange) error {
+	return AnimateWith(cfg, palette, sink, AnimateOptions{Range: frameRange})
+}
+
+// AnimateOptions controls which frames are rendered, and how.
+type AnimateOptions struct {
+	// Range selects the frames to render; see AnimateRange.
+	Range FrameRange
+	// Checkpoint, if set, stores every rendered frame and reuses the frames stored by a previous run.
+	Checkpoint *Checkpoint
+}
+
+// AnimateWith renders the frames selected by the options.
+func AnimateWith(cfg AnimationConfig, palette color.Palette, sink FrameSink, opts AnimateOptions) error {
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("validating animation config: %w", err)
+	}
+	trueColor := false
+	if tcs, ok := sink.(TrueColorSink); ok {
+		trueColor = tcs.TrueColor()
+	}
+	a, err := newAnimator(cfg, palette, trueColor)
+	if err != nil {
+		return err
+	}
+	frameCount := a.FrameCount()
+	first, last := opts.Range.First, opts.Range.Last
+	if last == -1 {
+		last = frameCount - 1
+	}
+	if first < 0 || first > last || last >= frameCount {
+		return fmt.Errorf("frame range (%d-%d) is outside of the animation (0-%d)", opts.Range.First, opts.Range.Last, frameCount-1)
+	}
+	rendered := make(map[int]Frame)
+	for currentFrame := first; currentFrame <= last; currentFrame++ {
+		renderIndex, delay := a.FrameTiming(currentFrame)
+		frame, ok := rendered[renderIndex]
+		if !ok {
+			spec, err := a.frameSpec(a.timeline.FrameTime(renderIndex))
+			if err != nil {
+				return fmt.Errorf("specifying (frame %d/%d): %w", currentFrame, frameCount, err)
+			}
+			img, err := a.checkpointedFrame(opts.Checkpoint, renderIndex, spec, func() {
+				_, _ = fmt.Fprintf(os.Stderr, "rendering %d/%d\n", currentFrame+1, frameCount)
+			})
+			if err != nil {
+				return fmt.Errorf("rendering (frame %d/%d): %w", currentFrame, frameCount, err)
+			}
+			frame = Frame{
+				Image:  img,
+				Render: spec.Render,
+			}
+			if mirror := frameCount - renderIndex; cfg.LoopMode == LoopModePingPong && mirror > currentFrame && mirror <= last {
+				// This frame will be shown again on the way back.
+				rendered[renderIndex] = frame
+			}
+		}
+		frame.Index = currentFrame
+		frame.Count = last - first + 1
+		frame.Delay = delay
+		if len(cfg.Overlays) > 0 {
+			frame.Image = drawOverlays(frame.Image, cfg.Overlays, overlayInfo{
+				Render: frame.Render,
+				Frame:  currentFrame,
+				Frames: frameCount,
+			})
+		}
+		if err := sink.WriteFrame(frame); err != nil {
+			return fmt.Errorf("writing frame (%d/%d): %w", currentFrame, frameCount, err)
+		}
 	}
 	return nil
 }
+
+// FrameCount returns the number of frames in the full animation.
+func FrameCount(cfg AnimationConfig, palette color.Palette) (int, error) {
+	if err := cfg.Validate(); err != nil {
+		return 0, fmt.Errorf("validating animation config: %w", err)
+	}
+	a, err := newAnimator(cfg, palette, false)
+	if err != nil {
+		return 0, err
+	}
+	return a.FrameCount(), nil
+}
+
+// animator renders the frames of an animation, reusing the previous frame when the view has not changed.
+type animator struct {
+	cfg         AnimationConfig
+	palette     color.Palette
+	trueColor   bool
+	cameraPath  *CameraPath
+	palettes    *paletteTrack
+	timeline    Timeline
+	duration    time.Duration
+	prevCfg     RenderConfig
+	prevPalette color.Palette
+	prevSamples []frameSpec
+	prevImg     image.Image
+}
+
+func newAnimator(cfg AnimationConfig, palette color.Palette, trueColor bool) (*animator, error) {
+	palette, err := cfg.BasePalette(palette)
+	if err != nil {
+		return nil, err
+	}
+	keyframes, err := cfg.keyframes(palette)
+	if err != nil {
+		return nil, fmt.Errorf("creating palettes: %w", err)
+	}
+	cameraPath, err := NewCameraPath(keyframes, cfg.Interpolation)
+	if err != nil {
+		return nil, fmt.Errorf("creating camera path: %w", err)
+	}
+	palettes := newPaletteTrack(cameraPath)
+	duration := cameraPath.Duration()
+	if duration == 0 && cfg.CycleSpeed != 0 {
+		// A still view lasts exactly one palette rotation, so that it loops seamlessly.
+		duration, err = cyclePeriod(palette, cfg.CycleSpeed)
+		if err != nil {
+			return nil, fmt.Errorf("determining cycle period: %w", err)
+		}
+	}
+	a := &animator{
+		cfg:        cfg,
+		palette:    palette,
+		trueColor:  trueColor,
+		cameraPath: cameraPath,
+		palettes:   palettes,
+		timeline:   NewTimeline(duration, cfg.FPS),
+		duration:   duration,
+	}
+	if cfg.SeamlessLoop && cfg.LoopMode != LoopModePingPong {
+		if err := a.checkSeamless(); err != nil {
+			return nil, fmt.Errorf("checking seamless loop: %w", err)
+		}
+	}
+	return a, nil
+}
+
+// FrameCount returns the number of frames in the full animation, including the reverse part of a ping-pong loop.
+func (a *animator) FrameCount() int {
+	if a.cfg.LoopMode == LoopModePingPong {
+		// Play forward up to and including the last keyframe, then backwards up to but excluding the first.
+		return 2 * a.timeline.FrameCount()
+	}
+	if a.closingFrame() {
+		return a.timeline.FrameCount() + 1
+	}
+	return a.timeline.FrameCount()
+}
+
+// closingFrame reports whether the animation ends with an extra frame showing the last keyframe.
+// The timeline frames stop just short of it, which is what a loop needs to not show the first view twice;
+// an animation that plays only once has to end on the last keyframe instead.
+func (a *animator) closingFrame() bool {
+	return a.cfg.LoopCount == -1 && a.cfg.LoopMode != LoopModePingPong && a.duration > 0
+}
+
+// FrameTiming maps a frame index in the full animation to the index on the timeline of the frame to render,
+// and how long it is shown. In ping-pong mode, the second half maps back onto the first.
+// The closing frame is rendered at the end of the timeline, and shown for one frame period.
+func (a *animator) FrameTiming(index int) (int, time.Duration) {
+	forwardCount := a.timeline.FrameCount()
+	if index < forwardCount {
+		return index, a.timeline.FrameDelay(index)
+	}
+	if a.closingFrame() {
+		return forwardCount, time.Second / time.Duration(a.cfg.FPS)
+	}
+	frameCount := 2 * forwardCount
+	return frameCount - index, a.timeline.FrameDelay(frameCount - 1 - index)
+}
+
+// checkSeamless verifies that the frame following the last frame would be identical to the first,
+// so that the animation loops without a visible jump.
+func (a *animator) checkSeamless() error {
+	first := a.cameraPath.At(0)
+	last := a.cameraPath.At(a.duration)
+	const epsilon = 1e-9
+	if math.Abs(first.Zoom-last.Zoom) > epsilon*first.Zoom ||
+		math.Abs(first.TargetX-last.TargetX) > epsilon ||
+		math.Abs(first.TargetY-last.TargetY) > epsilon {
+		return fmt.Errorf("last view %+v does not match first view %+v", last, first)
+	}
+	if first.MaxIterations != last.MaxIterations {
+		return fmt.Errorf("last MaxIterations (%d) does not match first (%d)", last.MaxIterations, first.MaxIterations)
+	}
+	if first.Mapping.withDefaults() != last.Mapping.withDefaults() {
+		return fmt.Errorf("last Mapping %+v does not match first %+v", last.Mapping, first.Mapping)
+	}
+	if !samePalette(a.palettes.At(0), a.palettes.At(a.duration)) {
+		return fmt.Errorf("last palette does not match first")
+	}
+	if a.cfg.CycleSpeed != 0 {
+		rotation := math.Mod(a.cfg.CycleSpeed*a.duration.Seconds(), float64(len(a.palette)-1))
+		if math.Abs(rotation) > epsilon && math.Abs(math.Abs(rotation)-float64(len(a.palette)-1)) > epsilon {
+			return fmt.Errorf("palette ends up rotated by %f entries", rotation)
+		}
+	}
+	return nil
+}
+
+// frameSpec contains everything that determines the image of a single frame.
+type frameSpec struct {
+	Render  RenderConfig
+	Palette color.Palette
+	// Samples contains the sub-frames that are averaged into a motion blurred frame.
+	Samples []frameSpec
+}
+
+// frameSpec determines how to render the frame at timeline position t.
+func (a *animator) frameSpec(t time.Duration) (frameSpec, error) {
+	cfg := a.cfg
+	if t > a.duration {
+		t = a.duration
+	}
+	renderCfg, err := cfg.renderConfig(a.cameraPath.At(t))
+	if err != nil {
+		return frameSpec{}, fmt.Errorf("determining render config: %w", err)
+	}
+	spec := frameSpec{
+		Render:  renderCfg,
+		Palette: renderCfg.Mapping.withInterior(cfg.paletteAt(a.palettes, t)),
+	}
+	if cfg.MotionBlurSamples > 1 {
+		samples, err := cfg.motionBlurSamples(a.cameraPath, a.palettes, t, a.timeline.FrameDelay(a.timeline.FrameIndex(t)), a.duration)
+		if err != nil {
+			return frameSpec{}, fmt.Errorf("determining motion blur samples: %w", err)
+		}
+		spec.Samples = samples
+	}
+	return spec, nil
+}
+
+// renderFrame renders a frame, reusing the previous frame where possible.
+func (a *animator) renderFrame(spec frameSpec) (image.Image, error) {
+	cfg := a.cfg
+	unchanged := a.prevImg != nil && spec.Render == a.prevCfg
+	paletteUnchanged := samePalette(spec.Palette, a.prevPalette)
+	samplesUnchanged := a.prevImg != nil && sameSamples(spec.Samples, a.prevSamples)
+	a.prevCfg = spec.Render
+	a.prevPalette = spec.Palette
+	a.prevSamples = spec.Samples
+	switch {
+	case len(spec.Samples) > 0:
+		// The sub-frames are blended, so the previous frame can only be reused if none of them changed.
+		if samplesUnchanged {
+			return a.prevImg, nil
+		}
+		img, err := cfg.renderMotionBlurred(spec.Samples)
+		if err != nil {
+			return nil, fmt.Errorf("rendering motion blur: %w", err)
+		}
+		a.prevImg = img
+		if !a.trueColor {
+			paletted := image.NewPaletted(img.Rect, spec.Palette)
+			draw.Draw(paletted, paletted.Rect, img, image.Point{}, draw.Src)
+			a.prevImg = paletted
+		}
+		return a.prevImg, nil
+	case a.trueColor:
+		if unchanged && paletteUnchanged {
+			return a.prevImg, nil
+		}
+		img := image.NewRGBA(image.Rect(0, 0, cfg.Width, cfg.Height))
+		if err := RenderRGBA(spec.Render, img, spec.Palette); err != nil {
+			return nil, err
+		}
+		a.prevImg = img
+		return img, nil
+	default:
+		var img *image.Paletted
+		if unchanged {
+			// The view has not changed, so the index image can be reused.
+			prevImg := a.prevImg.(*image.Paletted)
+			img = &image.Paletted{
+				Pix:    prevImg.Pix,
+				Stride: prevImg.Stride,
+				Rect:   prevImg.Rect,
+			}
+		} else {
+			img = image.NewPaletted(image.Rect(0, 0, cfg.Width, cfg.Height), a.palette)
+			if err := Render(spec.Render, img, a.palette); err != nil {
+				return nil, err
+			}
+		}
+		// A cycled palette is written to the GIF as a local color table.
+		img.Palette = spec.Palette
+		a.prevImg = img
+		return img, nil
+	}
+}
+
+// checkpointedFrame loads the frame from the checkpoint if it was stored with the same spec,
+// and renders and stores it otherwise. A nil checkpoint always renders.
+func (a *animator) checkpointedFrame(c *Checkpoint, renderIndex int, spec frameSpec, rendering func()) (image.Image, error) {
+	if c == nil {
+		rendering()
+		return a.renderFrame(spec)
+	}
+	key, err := a.frameKey(spec)
+	if err != nil {
+		return nil, fmt.Errorf("hashing frame: %w", err)
+	}
+	img, ok, err := c.Load(renderIndex, key)
+	if err != nil {
+		return nil, fmt.Errorf("loading checkpoint: %w", err)
+	}
+	if _, isPaletted := img.(*image.Paletted); ok && (isPaletted || a.trueColor) {
+		a.prevImg = img
+		a.prevCfg = spec.Render
+		a.prevPalette = spec.Palette
+		a.prevSamples = spec.Samples
+		return img, nil
+	}
+	rendering()
+	img, err = a.renderFrame(spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Store(renderIndex, key, img); err != nil {
+		return nil, fmt.Errorf("storing checkpoint: %w", err)
+	}
+	return img, nil
+}
+
+// frameKey identifies the image of a frame in a checkpoint; it covers everything that goes into rendering it.
+func (a *animator) frameKey(spec frameSpec) (string, error) {
+	return hashJSON(struct {
+		Width     int
+		Height    int
+		TrueColor bool
+		Spec      frameSpec
+	}{a.cfg.Width, a.cfg.Height, a.trueColor, spec})
+}
+
+// paletteAt returns the palette at timeline position t, including the palette cycling.
+func (cfg AnimationConfig) paletteAt(palettes *paletteTrack, t time.Duration) color.Palette {
+	palette := palettes.At(t)
+	if cfg.CycleSpeed != 0 {
+		palette = CyclePalette(palette, cfg.CycleSpeed*t.Seconds())
+	}
+	return palette
+}
+
+// keyframes returns the elements of resolvedPath, in which every Hold is turned into an extra element at the same position.
+func (cfg AnimationConfig) keyframes(base color.Palette) ([]AnimationConfigPathElement, error) {
+	path, err := cfg.resolvedPath(base)
+	if err != nil {
+		return nil, err
+	}
+	var keyframes []AnimationConfigPathElement
+	for _, pe := range path {
+		keyframes = append(keyframes, pe)
+		if pe.Hold > 0 {
+			hold := pe
+			hold.Duration = pe.Hold
+			hold.Hold = 0
+			hold.Easing = EaseLinear
+			hold.Transition = ""
+			keyframes = append(keyframes, hold)
+		}
+	}
+	return keyframes, nil
+}
+
+// resolvedPath returns a copy of the Path, with MaxIterations, Mapping and the palette set on every element.
+// Every element starts from the Mapping and palette of the element before it, or of the animation for the first.
+func (cfg AnimationConfig) resolvedPath(base color.Palette) ([]AnimationConfigPathElement, error) {
+	path := make([]AnimationConfigPathElement, len(cfg.Path))
+	mapping := cfg.Mapping.withDefaults()
+	palette, space := base, ColorSpaceOKLab
+	for i, pe := range cfg.Path {
+		if pe.MaxIterations == 0 {
+			pe.MaxIterations = cfg.MaxIterations
+		}
+		if pe.Mapping != nil {
+			mapping = pe.Mapping.merge(mapping).withDefaults()
+		}
+		elementMapping := mapping
+		pe.Mapping = &elementMapping
+		if pe.Palette != nil {
+			p, err := pe.Palette.Palette(len(base))
+			if err != nil {
+				return nil, fmt.Errorf("path element (%d): creating palette: %w", i, err)
+			}
+			if len(p) != len(base) {
+				return nil, fmt.Errorf("path element (%d): palette size (%d) differs from the animation palette (%d)", i, len(p), len(base))
+			}
+			palette = p
+			space = pe.Palette.ColorSpace
+			if space == "" {
+				space = ColorSpaceOKLab
+			}
+		}
+		pe.palette = palette
+		pe.paletteSpace = space
+		path[i] = pe
+	}
+	return path, nil
+}
+
+// renderConfig applies the IterationMode to the camera state.
+func (cfg AnimationConfig) renderConfig(state CameraState) (RenderConfig, error) {
+	rc := state.RenderConfig()
+	limit := cfg.MaxIterationsLimit
+	if limit == 0 {
+		limit = 16 * cfg.MaxIterations
+	}
+	switch cfg.IterationMode {
+	case "", IterationModeFixed:
+		return rc, nil
+	case IterationModeAuto:
+		rc.MaxIterations = AutoMaxIterations(rc.MaxIterations, rc.Zoom)
+	case IterationModeAdaptive:
+		rc.MaxIterations = AutoMaxIterations(rc.MaxIterations, rc.Zoom)
+		if rc.MaxIterations > limit {
+			rc.MaxIterations = limit
+		}
+		iterations, err := AdaptiveMaxIterations(rc, limit)
+		if err != nil {
+			return RenderConfig{}, fmt.Errorf("adapting iterations: %w", err)
+		}
+		rc.MaxIterations = iterations
+	default:
+		return RenderConfig{}, fmt.Errorf("unknown iteration mode (%s)", cfg.IterationMode)
+	}
+	if rc.MaxIterations > limit {
+		rc.MaxIterations = limit
+	}
+	return rc, nil
+}
@@ -0,0 +1,185 @@
+package mandelbrot
+
+import (
+	"github.com/stretchr/testify/require"
+	"image/color"
+	"testing"
+	"time"
+)
+
+type memorySink struct {
+	frames []Frame
+}
+
+func (s *memorySink) WriteFrame(frame Frame) error {
+	s.frames = append(s.frames, frame)
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	return nil
+}
+
+func TestAnimatePingPong(t *testing.T) {
+	cfg := AnimationConfig{
+		Width:         8,
+		Height:        6,
+		FPS:           10,
+		MaxIterations: 50,
+		LoopMode:      LoopModePingPong,
+		Path: []AnimationConfigPathElement{
+			{Zoom: 1, TargetX: 0.5, TargetY: 0.5},
+			{Zoom: 4, TargetX: 0.4, TargetY: 0.4, Duration: 500 * time.Millisecond, Hold: 200 * time.Millisecond},
+		},
+	}
+	palette := Gradient(color.RGBA{B: 255, A: 255}, color.Black, 16)
+	var sink memorySink
+	require.NoError(t, Animate(cfg, palette, &sink))
+	require.Len(t, sink.frames, 14)
+	var total time.Duration
+	for i, frame := range sink.frames {
+		require.Equal(t, i, frame.Index)
+		require.Equal(t, 14, frame.Count)
+		total += frame.Delay
+	}
+	require.Equal(t, 1400*time.Millisecond, total)
+	// The second half mirrors the first, around the last keyframe.
+	for i := 1; i < 7; i++ {
+		require.Equal(t, sink.frames[7-i].Render, sink.frames[7+i].Render, "frame %d", 7+i)
+	}
+	require.Equal(t, 4.0, sink.frames[7].Render.Zoom)
+	require.Equal(t, 4.0, sink.frames[6].Render.Zoom)
+}
+
+func TestAnimateClosingFrame(t *testing.T) {
+	cfg := AnimationConfig{
+		Width:         8,
+		Height:        6,
+		FPS:           10,
+		MaxIterations: 50,
+		Path: []AnimationConfigPathElement{
+			{Zoom: 1, TargetX: 0.5, TargetY: 0.5},
+			{Zoom: 4, TargetX: 0.4, TargetY: 0.4, Duration: 400 * time.Millisecond},
+		},
+	}
+	palette := Gradient(color.RGBA{B: 255, A: 255}, color.Black, 16)
+	var looping memorySink
+	require.NoError(t, Animate(cfg, palette, &looping))
+	// A loop stops short of the last keyframe, which would be followed by the first.
+	require.Len(t, looping.frames, 4)
+	require.Less(t, looping.frames[3].Render.Zoom, 4.0)
+
+	cfg.LoopCount = -1
+	var once memorySink
+	require.NoError(t, Animate(cfg, palette, &once))
+	require.Len(t, once.frames, 5)
+	for i := 0; i < 4; i++ {
+		require.Equal(t, looping.frames[i].Render, once.frames[i].Render, "frame %d", i)
+	}
+	last := once.frames[4]
+	require.Equal(t, 4.0, last.Render.Zoom)
+	require.Equal(t, 0.4, last.Render.TargetX)
+	require.Equal(t, 100*time.Millisecond, last.Delay)
+	count, err := FrameCount(cfg, palette)
+	require.NoError(t, err)
+	require.Equal(t, 5, count)
+}
+
+func TestAnimateRange(t *testing.T) {
+	cfg := AnimationConfig{
+		Width:         8,
+		Height:        6,
+		FPS:           10,
+		MaxIterations: 50,
+		LoopMode:      LoopModePingPong,
+		Interpolation: PathInterpolationCatmullRom,
+		Path: []AnimationConfigPathElement{
+			{Zoom: 1, TargetX: 0.5, TargetY: 0.5},
+			{Zoom: 4, TargetX: 0.4, TargetY: 0.4, Duration: 500 * time.Millisecond},
+			{Zoom: 9, TargetX: 0.3, TargetY: 0.45, Duration: 300 * time.Millisecond},
+		},
+	}
+	palette := Gradient(color.RGBA{B: 255, A: 255}, color.Black, 16)
+	var full memorySink
+	require.NoError(t, Animate(cfg, palette, &full))
+	count, err := FrameCount(cfg, palette)
+	require.NoError(t, err)
+	require.Len(t, full.frames, count)
+
+	var parts memorySink
+	for _, fr := range []FrameRange{{0, 4}, {5, 11}, {12, -1}} {
+		var part memorySink
+		require.NoError(t, AnimateRange(cfg, palette, &part, fr))
+		for _, frame := range part.frames {
+			require.Equal(t, len(part.frames), frame.Count)
+		}
+		parts.frames = append(parts.frames, part.frames...)
+	}
+	require.Len(t, parts.frames, count)
+	for i := range full.frames {
+		require.Equal(t, full.frames[i].Index, parts.frames[i].Index)
+		require.Equal(t, full.frames[i].Delay, parts.frames[i].Delay)
+		require.Equal(t, full.frames[i].Render, parts.frames[i].Render)
+		require.Equal(t, full.frames[i].Image, parts.frames[i].Image)
+	}
+	require.Error(t, AnimateRange(cfg, palette, &memorySink{}, FrameRange{First: 3, Last: count}))
+}
+
+func TestAnimateCheckpoint(t *testing.T) {
+	cfg := AnimationConfig{
+		Width:         8,
+		Height:        6,
+		FPS:           10,
+		MaxIterations: 50,
+		Path: []AnimationConfigPathElement{
+			{Zoom: 1, TargetX: 0.5, TargetY: 0.5},
+			{Zoom: 4, TargetX: 0.4, TargetY: 0.4, Duration: 500 * time.Millisecond},
+			{Zoom: 9, TargetX: 0.3, TargetY: 0.45, Duration: 300 * time.Millisecond},
+		},
+	}
+	palette := Gradient(color.RGBA{B: 255, A: 255}, color.Black, 16)
+	dir := t.TempDir()
+
+	checkpoint, err := OpenCheckpoint(dir)
+	require.NoError(t, err)
+	var first memorySink
+	require.NoError(t, AnimateWith(cfg, palette, &first, AnimateOptions{Range: FrameRange{0, -1}, Checkpoint: checkpoint}))
+
+	// Changing the last keyframe only affects the frames after the second keyframe.
+	changed := cfg
+	changed.Path = append([]AnimationConfigPathElement(nil), cfg.Path...)
+	changed.Path[2].Zoom = 16
+	before, err := newAnimator(cfg, palette, false)
+	require.NoError(t, err)
+	after, err := newAnimator(changed, palette, false)
+	require.NoError(t, err)
+	for i := 0; i < before.FrameCount(); i++ {
+		beforeSpec, err := before.frameSpec(before.timeline.FrameTime(i))
+		require.NoError(t, err)
+		afterSpec, err := after.frameSpec(after.timeline.FrameTime(i))
+		require.NoError(t, err)
+		beforeKey, err := before.frameKey(beforeSpec)
+		require.NoError(t, err)
+		afterKey, err := after.frameKey(afterSpec)
+		require.NoError(t, err)
+		if i <= 5 {
+			require.Equal(t, beforeKey, afterKey, "frame %d", i)
+		} else {
+			require.NotEqual(t, beforeKey, afterKey, "frame %d", i)
+		}
+	}
+
+	// Resuming from the checkpoint gives the same frames as rendering from scratch.
+	checkpoint, err = OpenCheckpoint(dir)
+	require.NoError(t, err)
+	var resumed, fresh memorySink
+	require.NoError(t, AnimateWith(changed, palette, &resumed, AnimateOptions{Range: FrameRange{0, -1}, Checkpoint: checkpoint}))
+	require.NoError(t, Animate(changed, palette, &fresh))
+	require.Len(t, resumed.frames, len(fresh.frames))
+	for i := range fresh.frames {
+		require.Equal(t, fresh.frames[i].Image, resumed.frames[i].Image, "frame %d", i)
+		if i <= 5 {
+			require.Equal(t, first.frames[i].Image, resumed.frames[i].Image, "frame %d", i)
+		}
+	}
+}
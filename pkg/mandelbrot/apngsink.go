@@ -0,0 +1,48 @@
+package mandelbrot
+
+import (
+	"fmt"
+	"github.com/PieterD/brot/pkg/apng"
+	"io"
+)
+
+// APNGSink writes frames to an animated PNG, in true color and with exact frame delays.
+type APNGSink struct {
+	w       io.Writer
+	width   int
+	height  int
+	encoder *apng.Encoder
+	// Plays is the number of times the animation is played; 0 plays it forever.
+	// It must be set before the first frame is written.
+	Plays int
+}
+
+func NewAPNGSink(w io.Writer, width, height int) *APNGSink {
+	return &APNGSink{
+		w:      w,
+		width:  width,
+		height: height,
+	}
+}
+
+func (s *APNGSink) TrueColor() bool {
+	return true
+}
+
+func (s *APNGSink) WriteFrame(frame Frame) error {
+	if s.encoder == nil {
+		// The frame count has to be known before the first frame is written.
+		s.encoder = apng.NewEncoder(s.w, s.width, s.height, frame.Count, s.Plays)
+	}
+	if err := s.encoder.WriteFrame(frame.Image, frame.Delay); err != nil {
+		return fmt.Errorf("encoding frame (%d): %w", frame.Index, err)
+	}
+	return nil
+}
+
+func (s *APNGSink) Close() error {
+	if s.encoder == nil {
+		return fmt.Errorf("no frames written")
+	}
+	return s.encoder.Close()
+}
@@ -0,0 +1,52 @@
+package mandelbrot
+
+import (
+	"fmt"
+	"github.com/PieterD/brot/pkg/avi"
+	"io"
+)
+
+// AVISink writes frames as Motion-JPEG video in an AVI file, for previews that play in any media player.
+// The video has a fixed frame rate, so frames are repeated or dropped to match their delays.
+type AVISink struct {
+	writer   *avi.Writer
+	repeater frameRepeater
+}
+
+// NewAVISink writes the AVI headers; quality is the JPEG quality, between 1 and 100.
+func NewAVISink(ws io.WriteSeeker, width, height, fps, quality int) (*AVISink, error) {
+	writer, err := avi.NewWriter(ws, width, height, fps, quality)
+	if err != nil {
+		return nil, fmt.Errorf("creating AVI writer: %w", err)
+	}
+	return &AVISink{
+		writer: writer,
+		repeater: frameRepeater{
+			fps: fps,
+		},
+	}, nil
+}
+
+func (s *AVISink) TrueColor() bool {
+	return true
+}
+
+func (s *AVISink) WriteFrame(frame Frame) error {
+	repeats := s.repeater.Next(frame.Delay)
+	if repeats == 0 {
+		return nil
+	}
+	if err := s.writer.WriteFrame(frame.Image); err != nil {
+		return fmt.Errorf("writing frame (%d): %w", frame.Index, err)
+	}
+	for i := 1; i < repeats; i++ {
+		if err := s.writer.RepeatFrame(); err != nil {
+			return fmt.Errorf("repeating frame (%d): %w", frame.Index, err)
+		}
+	}
+	return nil
+}
+
+func (s *AVISink) Close() error {
+	return s.writer.Close()
+}
@@ -0,0 +1,56 @@
+package mandelbrot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/require"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAVISink(t *testing.T) {
+	const width, height = 16, 8
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	filePath := filepath.Join(t.TempDir(), "test.avi")
+	f, err := os.Create(filePath)
+	require.NoError(t, err)
+	sink, err := NewAVISink(f, width, height, 25, 90)
+	require.NoError(t, err)
+	require.True(t, sink.TrueColor())
+	require.NoError(t, sink.WriteFrame(Frame{Index: 0, Delay: 40 * time.Millisecond, Image: solidRGBA(width, height, red)}))
+	// Shown for two frame periods, so it is written twice.
+	require.NoError(t, sink.WriteFrame(Frame{Index: 1, Delay: 80 * time.Millisecond, Image: solidRGBA(width, height, blue)}))
+	require.NoError(t, sink.Close())
+	require.NoError(t, f.Close())
+
+	b, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	le := binary.LittleEndian
+	require.Equal(t, "RIFF", string(b[0:4]))
+	require.Equal(t, "AVI ", string(b[8:12]))
+	want := []color.RGBA{red, blue, blue}
+	require.Equal(t, uint32(len(want)), le.Uint32(b[48:52]))
+	moviEnd := 220 + int(le.Uint32(b[216:220]))
+	require.Equal(t, "idx1", string(b[moviEnd:moviEnd+4]))
+	index := b[moviEnd+8:]
+	require.Len(t, index, 16*len(want))
+	for i, c := range want {
+		entry := index[16*i : 16*(i+1)]
+		offset := 220 + int(le.Uint32(entry[8:12]))
+		size := int(le.Uint32(entry[12:16]))
+		require.Equal(t, "00dc", string(b[offset:offset+4]))
+		img, err := jpeg.Decode(bytes.NewReader(b[offset+8 : offset+8+size]))
+		require.NoError(t, err)
+		require.Equal(t, image.Rect(0, 0, width, height), img.Bounds())
+		got := rgba(img.At(width/2, height/2))
+		require.InDelta(t, c.R, got.R, 8, "frame %d", i)
+		require.InDelta(t, c.G, got.G, 8, "frame %d", i)
+		require.InDelta(t, c.B, got.B, 8, "frame %d", i)
+	}
+}
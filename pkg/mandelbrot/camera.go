@@ -0,0 +1,225 @@
+package mandelbrot
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	PathInterpolationLinear     = "linear"
+	PathInterpolationCatmullRom = "catmull-rom"
+)
+
+const (
+	// TransitionSmoothZoom moves between two keyframes along the optimal zoom-out, pan, zoom-in trajectory.
+	TransitionSmoothZoom = "smooth-zoom"
+)
+
+// CameraState is the view of a single frame.
+type CameraState struct {
+	Zoom          float64
+	TargetX       float64
+	TargetY       float64
+	MaxIterations int
+	Mapping       ColorMapping
+}
+
+func (cs CameraState) RenderConfig() RenderConfig {
+	return RenderConfig{
+		MaxIterations: cs.MaxIterations,
+		Zoom:          cs.Zoom,
+		TargetX:       cs.TargetX,
+		TargetY:       cs.TargetY,
+		Mapping:       cs.Mapping,
+	}
+}
+
+// CameraPath is a continuous camera trajectory through all keyframes of an animation.
+type CameraPath struct {
+	keyframes []AnimationConfigPathElement
+	// starts contains the timeline position of every keyframe.
+	starts        []time.Duration
+	interpolation string
+	// Tangents per keyframe, in units per second, used by spline interpolation.
+	logZoomTangents []float64
+	xTangents       []float64
+	yTangents       []float64
+	// smoothZooms contains, for every keyframe using TransitionSmoothZoom, the trajectory leading up to it.
+	smoothZooms []*smoothZoom
+}
+
+func NewCameraPath(path []AnimationConfigPathElement, interpolation string) (*CameraPath, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("at least one path element is required")
+	}
+	if interpolation == "" {
+		interpolation = PathInterpolationLinear
+	}
+	cp := &CameraPath{
+		keyframes:     path,
+		starts:        make([]time.Duration, len(path)),
+		interpolation: interpolation,
+		smoothZooms:   make([]*smoothZoom, len(path)),
+	}
+	for i := 1; i < len(path); i++ {
+		cp.starts[i] = cp.starts[i-1] + path[i].Duration
+		switch path[i].Transition {
+		case "":
+		case TransitionSmoothZoom:
+			cp.smoothZooms[i] = newSmoothZoom(keyframeState(path[i-1]), keyframeState(path[i]))
+		default:
+			return nil, fmt.Errorf("unknown transition (%s) for path element (%d)", path[i].Transition, i)
+		}
+	}
+	switch interpolation {
+	case PathInterpolationLinear:
+	case PathInterpolationCatmullRom:
+		cp.logZoomTangents = cp.tangents(func(pe AnimationConfigPathElement) float64 { return math.Log(pe.Zoom) })
+		cp.xTangents = cp.tangents(func(pe AnimationConfigPathElement) float64 { return pe.TargetX })
+		cp.yTangents = cp.tangents(func(pe AnimationConfigPathElement) float64 { return pe.TargetY })
+	default:
+		return nil, fmt.Errorf("unknown path interpolation (%s)", interpolation)
+	}
+	return cp, nil
+}
+
+// Duration returns the timeline position of the last keyframe.
+func (cp *CameraPath) Duration() time.Duration {
+	return cp.starts[len(cp.starts)-1]
+}
+
+// At returns the camera state at the given timeline position.
+// Positions outside the timeline are clamped to the first or last keyframe.
+func (cp *CameraPath) At(t time.Duration) CameraState {
+	if t <= 0 || len(cp.keyframes) == 1 {
+		return keyframeState(cp.keyframes[0])
+	}
+	if t >= cp.Duration() {
+		return keyframeState(cp.keyframes[len(cp.keyframes)-1])
+	}
+	i, u := cp.segment(t)
+	from := cp.keyframes[i-1]
+	to := cp.keyframes[i]
+	state := cp.between(i, u)
+	// An easing that overshoots only moves the camera; the iterations and mapping stay between those of the keyframes.
+	u = clamp01(u)
+	state.MaxIterations = int(math.Round(lerp(float64(from.MaxIterations), float64(to.MaxIterations), u)))
+	state.Mapping = lerpMapping(keyframeMapping(from), keyframeMapping(to), u)
+	return state
+}
+
+// segment returns the index of the keyframe the camera is moving towards at t, and the eased fraction of the way there.
+// Positions outside the timeline return the first or last keyframe, with a fraction of 1.
+func (cp *CameraPath) segment(t time.Duration) (int, float64) {
+	if t <= 0 || len(cp.keyframes) == 1 {
+		return 0, 1
+	}
+	if t >= cp.Duration() {
+		return len(cp.keyframes) - 1, 1
+	}
+	i := 1
+	for cp.starts[i] < t {
+		i++
+	}
+	easing := cp.keyframes[i].Easing
+	if easing == nil {
+		easing = EaseLinear
+	}
+	return i, easing(float64(t-cp.starts[i-1]) / float64(cp.keyframes[i].Duration))
+}
+
+// between returns the view at fraction u of the way from keyframe i-1 to keyframe i.
+func (cp *CameraPath) between(i int, u float64) CameraState {
+	from := cp.keyframes[i-1]
+	to := cp.keyframes[i]
+	if sz := cp.smoothZooms[i]; sz != nil {
+		return sz.At(u)
+	}
+	if sameView(from, to) {
+		return keyframeState(to)
+	}
+	switch cp.interpolation {
+	case PathInterpolationCatmullRom:
+		h := to.Duration.Seconds()
+		logZoom := hermite(math.Log(from.Zoom), math.Log(to.Zoom), cp.logZoomTangents[i-1]*h, cp.logZoomTangents[i]*h, u)
+		return CameraState{
+			Zoom:    math.Max(1, math.Exp(logZoom)),
+			TargetX: clamp01(hermite(from.TargetX, to.TargetX, cp.xTangents[i-1]*h, cp.xTangents[i]*h, u)),
+			TargetY: clamp01(hermite(from.TargetY, to.TargetY, cp.yTangents[i-1]*h, cp.yTangents[i]*h, u)),
+		}
+	default:
+		// An easing that overshoots can move the camera past either keyframe.
+		return CameraState{
+			Zoom:    math.Max(1, lerp(from.Zoom, to.Zoom, u)),
+			TargetX: clamp01(lerp(from.TargetX, to.TargetX, u)),
+			TargetY: clamp01(lerp(from.TargetY, to.TargetY, u)),
+		}
+	}
+}
+
+// tangents calculates Catmull-Rom tangents for every keyframe, taking the
+// differing segment durations into account so that speed is continuous across keyframes.
+// The first and last keyframes use one-sided differences.
+func (cp *CameraPath) tangents(value func(pe AnimationConfigPathElement) float64) []float64 {
+	n := len(cp.keyframes)
+	tangents := make([]float64, n)
+	if n < 2 {
+		return tangents
+	}
+	slope := func(a, b int) float64 {
+		dt := (cp.starts[b] - cp.starts[a]).Seconds()
+		if dt == 0 {
+			return 0
+		}
+		return (value(cp.keyframes[b]) - value(cp.keyframes[a])) / dt
+	}
+	tangents[0] = slope(0, 1)
+	tangents[n-1] = slope(n-2, n-1)
+	for i := 1; i < n-1; i++ {
+		// Come to a smooth stop next to a segment in which the camera does not move, such as a Hold.
+		// A keyframe that only shares some of its coordinates with a neighbour keeps moving through it.
+		if sameView(cp.keyframes[i-1], cp.keyframes[i]) || sameView(cp.keyframes[i], cp.keyframes[i+1]) {
+			continue
+		}
+		tangents[i] = slope(i-1, i+1)
+	}
+	return tangents
+}
+
+// sameView reports whether the camera is in the same place at both keyframes.
+func sameView(a, b AnimationConfigPathElement) bool {
+	return a.Zoom == b.Zoom && a.TargetX == b.TargetX && a.TargetY == b.TargetY
+}
+
+func keyframeState(pe AnimationConfigPathElement) CameraState {
+	return CameraState{
+		Zoom:          pe.Zoom,
+		TargetX:       pe.TargetX,
+		TargetY:       pe.TargetY,
+		MaxIterations: pe.MaxIterations,
+		Mapping:       keyframeMapping(pe),
+	}
+}
+
+func keyframeMapping(pe AnimationConfigPathElement) ColorMapping {
+	if pe.Mapping == nil {
+		return ColorMapping{}
+	}
+	return *pe.Mapping
+}
+
+// hermite evaluates the cubic Hermite curve between p0 and p1 with tangents m0 and m1 at u.
+func hermite(p0, p1, m0, m1, u float64) float64 {
+	u2 := u * u
+	u3 := u2 * u
+	return (2*u3-3*u2+1)*p0 + (u3-2*u2+u)*m0 + (-2*u3+3*u2)*p1 + (u3-u2)*m1
+}
+
+func lerp(from, to, u float64) float64 {
+	return from + (to-from)*u
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
@@ -0,0 +1,130 @@
+package mandelbrot
+
+import (
+	"github.com/stretchr/testify/require"
+	"image"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCameraPathKeyframes(t *testing.T) {
+	path := []AnimationConfigPathElement{
+		{Zoom: 1, TargetX: 0.5, TargetY: 0.5},
+		{Zoom: 10, TargetX: 0.4, TargetY: 0.4, Duration: 2 * time.Second},
+		{Zoom: 100, TargetX: 0.38, TargetY: 0.39, Duration: 1 * time.Second},
+		{Zoom: 10, TargetX: 0.4, TargetY: 0.45, Duration: 3 * time.Second},
+	}
+	for _, interpolation := range []string{PathInterpolationLinear, PathInterpolationCatmullRom} {
+		t.Run(interpolation, func(t *testing.T) {
+			cp, err := NewCameraPath(path, interpolation)
+			require.NoError(t, err)
+			require.Equal(t, 6*time.Second, cp.Duration())
+			var at time.Duration
+			for _, pe := range path {
+				at += pe.Duration
+				state := cp.At(at)
+				require.InDelta(t, pe.Zoom, state.Zoom, 1e-9)
+				require.InDelta(t, pe.TargetX, state.TargetX, 1e-9)
+				require.InDelta(t, pe.TargetY, state.TargetY, 1e-9)
+			}
+		})
+	}
+}
+
+func TestCameraPathOvershoot(t *testing.T) {
+	overshoot, err := ParseEasing("cubic-bezier(0.3,-0.6,0.7,1.6)")
+	require.NoError(t, err)
+	path := []AnimationConfigPathElement{
+		{Zoom: 1, TargetX: 0, TargetY: 0.5, MaxIterations: 50},
+		{Zoom: 10, TargetX: 1, TargetY: 0.5, MaxIterations: 100, Duration: time.Second, Easing: overshoot},
+	}
+	for _, interpolation := range []string{PathInterpolationLinear, PathInterpolationCatmullRom} {
+		t.Run(interpolation, func(t *testing.T) {
+			cp, err := NewCameraPath(path, interpolation)
+			require.NoError(t, err)
+			for at := time.Duration(0); at <= time.Second; at += 10 * time.Millisecond {
+				state := cp.At(at)
+				require.GreaterOrEqual(t, state.Zoom, 1.0, "at %v", at)
+				require.True(t, state.TargetX >= 0 && state.TargetX <= 1, "at %v: TargetX %f", at, state.TargetX)
+				require.True(t, state.MaxIterations >= 50 && state.MaxIterations <= 100, "at %v: MaxIterations %d", at, state.MaxIterations)
+				require.NoError(t, RenderRGBA(state.RenderConfig(), image.NewRGBA(image.Rect(0, 0, 4, 3)), DefaultPalette()))
+			}
+		})
+	}
+}
+
+func TestCameraPathSplineContinuity(t *testing.T) {
+	path := []AnimationConfigPathElement{
+		{Zoom: 1, TargetX: 0.5, TargetY: 0.5},
+		{Zoom: 10, TargetX: 0.4, TargetY: 0.4, Duration: 2 * time.Second},
+		{Zoom: 100, TargetX: 0.38, TargetY: 0.39, Duration: 1 * time.Second},
+	}
+	cp, err := NewCameraPath(path, PathInterpolationCatmullRom)
+	require.NoError(t, err)
+	const eps = time.Millisecond
+	knot := 2 * time.Second
+	before := (cp.At(knot).TargetX - cp.At(knot-eps).TargetX) / eps.Seconds()
+	after := (cp.At(knot+eps).TargetX - cp.At(knot).TargetX) / eps.Seconds()
+	require.InDelta(t, before, after, 1e-3)
+}
+
+func TestCameraPathSplineTangents(t *testing.T) {
+	const second = time.Second
+	var tests = []struct {
+		desc string
+		path []AnimationConfigPathElement
+		// zoomTangent is the expected tangent of the log zoom at the middle keyframe, in units per second.
+		zoomTangent float64
+	}{
+		{
+			desc: "pan at a constant zoom keeps zooming through",
+			path: []AnimationConfigPathElement{
+				{Zoom: 1, TargetX: 0.5, TargetY: 0.5},
+				{Zoom: 1, TargetX: 0.4, TargetY: 0.5, Duration: second},
+				{Zoom: 4, TargetX: 0.3, TargetY: 0.5, Duration: second},
+			},
+			zoomTangent: math.Log(4) / 2,
+		},
+		{
+			desc: "hold comes to a stop",
+			path: []AnimationConfigPathElement{
+				{Zoom: 1, TargetX: 0.5, TargetY: 0.5},
+				{Zoom: 2, TargetX: 0.4, TargetY: 0.5, Duration: second},
+				{Zoom: 2, TargetX: 0.4, TargetY: 0.5, Duration: second},
+			},
+			zoomTangent: 0,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			cp, err := NewCameraPath(test.path, PathInterpolationCatmullRom)
+			require.NoError(t, err)
+			require.InDelta(t, test.zoomTangent, cp.logZoomTangents[1], 1e-12)
+			// Halfway the first segment, the zoom follows the Catmull-Rom spline through all three keyframes.
+			from, to := test.path[0], test.path[1]
+			m0 := cp.logZoomTangents[0]
+			want := math.Exp(hermite(math.Log(from.Zoom), math.Log(to.Zoom), m0, test.zoomTangent, 0.5))
+			require.InDelta(t, math.Max(1, want), cp.At(second/2).Zoom, 1e-9)
+		})
+	}
+}
+
+func TestSmoothZoom(t *testing.T) {
+	from := CameraState{Zoom: 1000, TargetX: 0.2, TargetY: 0.3}
+	to := CameraState{Zoom: 1000, TargetX: 0.8, TargetY: 0.6}
+	sz := newSmoothZoom(from, to)
+	start := sz.At(0.000001)
+	end := sz.At(0.999999)
+	require.InDelta(t, from.TargetX, start.TargetX, 1e-3)
+	require.InDelta(t, from.Zoom, start.Zoom, 1)
+	require.InDelta(t, to.TargetY, end.TargetY, 1e-3)
+	require.InDelta(t, to.Zoom, end.Zoom, 1)
+	// Between two distant deep views, the camera should zoom out first.
+	require.Less(t, sz.At(0.5).Zoom, from.Zoom/10)
+
+	roundTrip := cameraFromView(cameraView(from))
+	require.InDelta(t, from.Zoom, roundTrip.Zoom, 1e-6)
+	require.InDelta(t, from.TargetX, roundTrip.TargetX, 1e-9)
+	require.InDelta(t, from.TargetY, roundTrip.TargetY, 1e-9)
+}
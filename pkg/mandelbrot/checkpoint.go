@@ -0,0 +1,175 @@
+package mandelbrot
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const checkpointManifestFile = "manifest.jsonl"
+
+// Checkpoint stores finished frames in a work directory, so that an interrupted render can be resumed.
+// Every frame is stored along with a key that covers everything that went into rendering it,
+// so that a change to the animation config only invalidates the frames that are actually affected.
+//
+// The manifest records the key of every stored frame, one JSON line per frame.
+// Lines are only appended, so that storing a frame does not rewrite the whole manifest;
+// when a frame is stored again, its last line wins.
+type Checkpoint struct {
+	dir    string
+	frames map[int]string
+}
+
+type checkpointEntry struct {
+	Frame int
+	Key   string
+}
+
+// OpenCheckpoint opens the work directory, creating it if it does not exist yet.
+// A manifest with superseded or partially written lines is compacted.
+func OpenCheckpoint(dir string) (*Checkpoint, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating work directory: %w", err)
+	}
+	c := &Checkpoint{
+		dir:    dir,
+		frames: make(map[int]string),
+	}
+	b, err := os.ReadFile(c.manifestPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest: %w", err)
+	}
+	// Every entry ends with a newline, so the last line is either empty, or an entry that was only partially appended.
+	lines := bytes.Split(b, []byte("\n"))
+	lines = lines[:len(lines)-1]
+	rewrite := len(b) > 0 && b[len(b)-1] != '\n'
+	for _, line := range lines {
+		var entry checkpointEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			rewrite = true
+			continue
+		}
+		c.frames[entry.Frame] = entry.Key
+	}
+	// Partial and superseded lines are dropped, so that the manifest does not keep growing.
+	if rewrite || len(lines) != len(c.frames) {
+		if err := c.compact(); err != nil {
+			return nil, fmt.Errorf("compacting manifest: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// Load returns the stored frame, if it was rendered with the same key.
+func (c *Checkpoint) Load(index int, key string) (image.Image, bool, error) {
+	if storedKey, ok := c.frames[index]; !ok || storedKey != key {
+		return nil, false, nil
+	}
+	f, err := os.Open(c.framePath(index))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("opening frame: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	img, err := png.Decode(f)
+	if err != nil {
+		// A frame that was only partially written is rendered again.
+		return nil, false, nil
+	}
+	return img, true, nil
+}
+
+// Store writes the frame to the work directory, and appends it to the manifest.
+func (c *Checkpoint) Store(index int, key string, img image.Image) error {
+	if err := writeFileAtomic(c.framePath(index), func(w io.Writer) error {
+		return png.Encode(w, img)
+	}); err != nil {
+		return fmt.Errorf("writing frame: %w", err)
+	}
+	line, err := json.Marshal(checkpointEntry{Frame: index, Key: key})
+	if err != nil {
+		return fmt.Errorf("encoding manifest entry: %w", err)
+	}
+	f, err := os.OpenFile(c.manifestPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening manifest: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("appending to manifest: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("closing manifest: %w", err)
+	}
+	c.frames[index] = key
+	return nil
+}
+
+func (c *Checkpoint) framePath(index int) string {
+	return filepath.Join(c.dir, fmt.Sprintf("frame_%05d.png", index))
+}
+
+func (c *Checkpoint) manifestPath() string {
+	return filepath.Join(c.dir, checkpointManifestFile)
+}
+
+// compact rewrites the manifest with a single line per frame.
+func (c *Checkpoint) compact() error {
+	indexes := make([]int, 0, len(c.frames))
+	for index := range c.frames {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, index := range indexes {
+		if err := enc.Encode(checkpointEntry{Frame: index, Key: c.frames[index]}); err != nil {
+			return fmt.Errorf("encoding manifest entry: %w", err)
+		}
+	}
+	return writeFileAtomic(c.manifestPath(), func(w io.Writer) error {
+		_, err := w.Write(buf.Bytes())
+		return err
+	})
+}
+
+// writeFileAtomic writes to a temporary file first, so that an interruption never leaves a partial file behind.
+func writeFileAtomic(filePath string, write func(w io.Writer) error) error {
+	tmpPath := filePath + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filePath)
+}
+
+// hashJSON returns a hex encoded hash of the JSON encoding of v.
+func hashJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,69 @@
+package mandelbrot
+
+import (
+	"github.com/stretchr/testify/require"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	frame := func(c color.RGBA) image.Image {
+		return solidRGBA(3, 2, c)
+	}
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+
+	c, err := OpenCheckpoint(dir)
+	require.NoError(t, err)
+	_, ok, err := c.Load(0, "a")
+	require.NoError(t, err)
+	require.False(t, ok, "empty checkpoint")
+	require.NoError(t, c.Store(0, "a", frame(red)))
+	require.NoError(t, c.Store(1, "b", frame(blue)))
+	require.NoError(t, c.Store(1, "c", frame(red)))
+
+	c, err = OpenCheckpoint(dir)
+	require.NoError(t, err)
+	img, ok, err := c.Load(0, "a")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, red, rgba(img.At(2, 1)))
+	_, ok, err = c.Load(0, "b")
+	require.NoError(t, err)
+	require.False(t, ok, "key mismatch")
+	_, ok, err = c.Load(1, "b")
+	require.NoError(t, err)
+	require.False(t, ok, "superseded key")
+	img, ok, err = c.Load(1, "c")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, red, rgba(img.At(0, 0)))
+	_, ok, err = c.Load(2, "a")
+	require.NoError(t, err)
+	require.False(t, ok, "frame that was never stored")
+
+	// An interrupted append leaves a partial line behind, which does not affect the other frames.
+	f, err := os.OpenFile(filepath.Join(dir, checkpointManifestFile), os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"Frame":2,"Ke`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	c, err = OpenCheckpoint(dir)
+	require.NoError(t, err)
+	_, ok, err = c.Load(1, "c")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoError(t, c.Store(2, "d", frame(blue)))
+
+	c, err = OpenCheckpoint(dir)
+	require.NoError(t, err)
+	for index, key := range []string{"a", "c", "d"} {
+		_, ok, err := c.Load(index, key)
+		require.NoError(t, err)
+		require.True(t, ok, "frame %d", index)
+	}
+}
@@ -0,0 +1,269 @@
+package mandelbrot
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+)
+
+const (
+	// ColorSpaceSRGB interpolates the gamma encoded sRGB values directly, as Gradient does.
+	ColorSpaceSRGB = "srgb"
+	// ColorSpaceLinearRGB interpolates in linear light, which avoids the dark, muddy midpoints of sRGB.
+	ColorSpaceLinearRGB = "linear-rgb"
+	// ColorSpaceHSL interpolates hue, saturation and lightness, taking the shortest way around the hue circle.
+	ColorSpaceHSL = "hsl"
+	// ColorSpaceHCL interpolates hue, chroma and lightness; the polar form of CIELAB.
+	ColorSpaceHCL    = "hcl"
+	ColorSpaceCIELAB = "cielab"
+	// ColorSpaceOKLab interpolates in the perceptually uniform OKLab space.
+	ColorSpaceOKLab = "oklab"
+)
+
+// colorVec holds the three components of a color in one of the color spaces.
+type colorVec [3]float64
+
+// D65 reference white, used by CIELAB.
+const (
+	whiteX = 0.95047
+	whiteY = 1.0
+	whiteZ = 1.08883
+)
+
+func validateColorSpace(space string) error {
+	switch space {
+	case ColorSpaceSRGB, ColorSpaceLinearRGB, ColorSpaceHSL, ColorSpaceHCL, ColorSpaceCIELAB, ColorSpaceOKLab:
+		return nil
+	default:
+		return fmt.Errorf("unknown color space (%s)", space)
+	}
+}
+
+// hueComponent returns the index of the component that is an angle in degrees, or -1 if there is none.
+func hueComponent(space string) int {
+	switch space {
+	case ColorSpaceHSL:
+		return 0
+	case ColorSpaceHCL:
+		return 2
+	default:
+		return -1
+	}
+}
+
+// toColorSpace converts c to the color space, along with its (straight) alpha between 0 and 1.
+func toColorSpace(space string, c color.Color) (colorVec, float64) {
+	n := color.NRGBA64Model.Convert(c).(color.NRGBA64)
+	srgb := colorVec{float64(n.R) / 0xffff, float64(n.G) / 0xffff, float64(n.B) / 0xffff}
+	alpha := float64(n.A) / 0xffff
+	switch space {
+	case ColorSpaceSRGB:
+		return srgb, alpha
+	case ColorSpaceHSL:
+		return srgbToHSL(srgb), alpha
+	}
+	linear := colorVec{srgbToLinear(srgb[0]), srgbToLinear(srgb[1]), srgbToLinear(srgb[2])}
+	switch space {
+	case ColorSpaceCIELAB:
+		return linearToLab(linear), alpha
+	case ColorSpaceHCL:
+		return labToLCh(linearToLab(linear)), alpha
+	case ColorSpaceOKLab:
+		return linearToOKLab(linear), alpha
+	default:
+		return linear, alpha
+	}
+}
+
+// fromColorSpace is the inverse of toColorSpace; colors outside of the sRGB gamut are clipped.
+func fromColorSpace(space string, v colorVec, alpha float64) color.RGBA {
+	var srgb colorVec
+	switch space {
+	case ColorSpaceSRGB:
+		srgb = v
+	case ColorSpaceHSL:
+		srgb = hslToSRGB(v)
+	default:
+		var linear colorVec
+		switch space {
+		case ColorSpaceCIELAB:
+			linear = labToLinear(v)
+		case ColorSpaceHCL:
+			linear = labToLinear(lchToLab(v))
+		case ColorSpaceOKLab:
+			linear = okLabToLinear(v)
+		default:
+			linear = v
+		}
+		srgb = colorVec{linearToSRGB(linear[0]), linearToSRGB(linear[1]), linearToSRGB(linear[2])}
+	}
+	channel := func(v float64) uint8 {
+		return uint8(math.Round(255 * clamp01(v)))
+	}
+	return color.RGBAModel.Convert(color.NRGBA{
+		R: channel(srgb[0]),
+		G: channel(srgb[1]),
+		B: channel(srgb[2]),
+		A: channel(alpha),
+	}).(color.RGBA)
+}
+
+// mixColors interpolates between two colors at fraction u, in the given color space.
+func mixColors(space string, from, to color.Color, u float64) color.RGBA {
+	v0, a0 := toColorSpace(space, from)
+	v1, a1 := toColorSpace(space, to)
+	var v colorVec
+	for i := range v {
+		v[i] = lerp(v0[i], v1[i], u)
+	}
+	if h := hueComponent(space); h >= 0 {
+		v[h] = lerpHue(v0, v1, h, u)
+	}
+	return fromColorSpace(space, v, lerp(a0, a1, u))
+}
+
+// lerpHue interpolates the hue component h along the shortest way around the circle.
+// The hue of a gray is meaningless, so the hue of the other color is used instead.
+// In both HSL and HCL, the saturation or chroma is the middle component.
+func lerpHue(v0, v1 colorVec, h int, u float64) float64 {
+	const grayEpsilon = 1e-6
+	switch {
+	case v0[1] < grayEpsilon:
+		return v1[h]
+	case v1[1] < grayEpsilon:
+		return v0[h]
+	}
+	diff := math.Mod(v1[h]-v0[h]+540, 360) - 180
+	return math.Mod(v0[h]+diff*u+360, 360)
+}
+
+func srgbToLinear(c float64) float64 {
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return math.Pow((c+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(c float64) float64 {
+	if c <= 0.0031308 {
+		return 12.92 * c
+	}
+	return 1.055*math.Pow(c, 1/2.4) - 0.055
+}
+
+func srgbToHSL(c colorVec) colorVec {
+	maxC := math.Max(c[0], math.Max(c[1], c[2]))
+	minC := math.Min(c[0], math.Min(c[1], c[2]))
+	l := (maxC + minC) / 2
+	d := maxC - minC
+	if d == 0 {
+		return colorVec{0, 0, l}
+	}
+	s := d / (1 - math.Abs(2*l-1))
+	var h float64
+	switch maxC {
+	case c[0]:
+		h = math.Mod((c[1]-c[2])/d+6, 6)
+	case c[1]:
+		h = (c[2]-c[0])/d + 2
+	default:
+		h = (c[0]-c[1])/d + 4
+	}
+	return colorVec{60 * h, s, l}
+}
+
+func hslToSRGB(c colorVec) colorVec {
+	h, s, l := c[0], c[1], c[2]
+	chroma := (1 - math.Abs(2*l-1)) * s
+	hp := math.Mod(h, 360) / 60
+	x := chroma * (1 - math.Abs(math.Mod(hp, 2)-1))
+	var r, g, b float64
+	switch {
+	case hp < 1:
+		r, g = chroma, x
+	case hp < 2:
+		r, g = x, chroma
+	case hp < 3:
+		g, b = chroma, x
+	case hp < 4:
+		g, b = x, chroma
+	case hp < 5:
+		r, b = x, chroma
+	default:
+		r, b = chroma, x
+	}
+	m := l - chroma/2
+	return colorVec{r + m, g + m, b + m}
+}
+
+func linearToLab(c colorVec) colorVec {
+	x := (0.4124564*c[0] + 0.3575761*c[1] + 0.1804375*c[2]) / whiteX
+	y := (0.2126729*c[0] + 0.7151522*c[1] + 0.0721750*c[2]) / whiteY
+	z := (0.0193339*c[0] + 0.1191920*c[1] + 0.9503041*c[2]) / whiteZ
+	f := func(t float64) float64 {
+		const delta = 6.0 / 29
+		if t > delta*delta*delta {
+			return math.Cbrt(t)
+		}
+		return t/(3*delta*delta) + 4.0/29
+	}
+	fx, fy, fz := f(x), f(y), f(z)
+	return colorVec{116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)}
+}
+
+func labToLinear(c colorVec) colorVec {
+	fy := (c[0] + 16) / 116
+	fx := fy + c[1]/500
+	fz := fy - c[2]/200
+	finv := func(t float64) float64 {
+		const delta = 6.0 / 29
+		if t > delta {
+			return t * t * t
+		}
+		return 3 * delta * delta * (t - 4.0/29)
+	}
+	x, y, z := finv(fx)*whiteX, finv(fy)*whiteY, finv(fz)*whiteZ
+	return colorVec{
+		3.2404542*x - 1.5371385*y - 0.4985314*z,
+		-0.9692660*x + 1.8760108*y + 0.0415560*z,
+		0.0556434*x - 0.2040259*y + 1.0572252*z,
+	}
+}
+
+// labToLCh converts CIELAB to lightness, chroma and hue in degrees.
+func labToLCh(c colorVec) colorVec {
+	h := math.Atan2(c[2], c[1]) * 180 / math.Pi
+	if h < 0 {
+		h += 360
+	}
+	return colorVec{c[0], math.Hypot(c[1], c[2]), h}
+}
+
+func lchToLab(c colorVec) colorVec {
+	h := c[2] * math.Pi / 180
+	return colorVec{c[0], c[1] * math.Cos(h), c[1] * math.Sin(h)}
+}
+
+// linearToOKLab implements the conversion by Björn Ottosson (2020).
+func linearToOKLab(c colorVec) colorVec {
+	l := math.Cbrt(0.4122214708*c[0] + 0.5363325363*c[1] + 0.0514459929*c[2])
+	m := math.Cbrt(0.2119034982*c[0] + 0.6806995451*c[1] + 0.1073969566*c[2])
+	s := math.Cbrt(0.0883024619*c[0] + 0.2817188376*c[1] + 0.6299787005*c[2])
+	return colorVec{
+		0.2104542553*l + 0.7936177850*m - 0.0040720468*s,
+		1.9779984951*l - 2.4285922050*m + 0.4505937099*s,
+		0.0259040371*l + 0.7827717662*m - 0.8086757660*s,
+	}
+}
+
+func okLabToLinear(c colorVec) colorVec {
+	l := c[0] + 0.3963377774*c[1] + 0.2158037573*c[2]
+	m := c[0] - 0.1055613458*c[1] - 0.0638541728*c[2]
+	s := c[0] - 0.0894841775*c[1] - 1.2914855480*c[2]
+	l, m, s = l*l*l, m*m*m, s*s*s
+	return colorVec{
+		4.0767416621*l - 3.3077115913*m + 0.2309699292*s,
+		-1.2684380046*l + 2.6097574011*m - 0.3413193965*s,
+		-0.0041960863*l - 0.7034186147*m + 1.7076147010*s,
+	}
+}
@@ -0,0 +1,39 @@
+package mandelbrot
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"time"
+)
+
+// CyclePalette rotates the palette by offset entries.
+// The last entry is the color of points inside the set, and is not rotated.
+func CyclePalette(palette color.Palette, offset float64) color.Palette {
+	cycled := make(color.Palette, len(palette))
+	copy(cycled, palette)
+	n := len(palette) - 1
+	if n <= 0 {
+		return cycled
+	}
+	shift := int(math.Floor(offset)) % n
+	if shift < 0 {
+		shift += n
+	}
+	for i := 0; i < n; i++ {
+		cycled[i] = palette[(i+shift)%n]
+	}
+	return cycled
+}
+
+// cyclePeriod returns the time it takes to rotate the entire palette once.
+// The palette needs at least one color besides the interior color to rotate.
+func cyclePeriod(palette color.Palette, speed float64) (time.Duration, error) {
+	if len(palette) < 2 {
+		return 0, fmt.Errorf("palette of (%d) entries has no colors to cycle", len(palette))
+	}
+	if speed == 0 {
+		return 0, fmt.Errorf("invalid cycle speed (%f)", speed)
+	}
+	return time.Duration(float64(len(palette)-1) / math.Abs(speed) * float64(time.Second)), nil
+}
@@ -0,0 +1,36 @@
+package mandelbrot
+
+import (
+	"github.com/stretchr/testify/require"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestCyclePalette(t *testing.T) {
+	a := color.RGBA{R: 1, A: 255}
+	b := color.RGBA{R: 2, A: 255}
+	c := color.RGBA{R: 3, A: 255}
+	inside := color.RGBA{A: 255}
+	palette := color.Palette{a, b, c, inside}
+	require.Equal(t, color.Palette{a, b, c, inside}, CyclePalette(palette, 0))
+	require.Equal(t, color.Palette{b, c, a, inside}, CyclePalette(palette, 1.5))
+	require.Equal(t, color.Palette{c, a, b, inside}, CyclePalette(palette, -1))
+	require.Equal(t, color.Palette{a, b, c, inside}, CyclePalette(palette, 3))
+}
+
+func TestCyclePeriod(t *testing.T) {
+	palette := Gradient(color.RGBA{A: 255}, color.RGBA{R: 255, A: 255}, 5)
+	period, err := cyclePeriod(palette, 2)
+	require.NoError(t, err)
+	require.Equal(t, 2*time.Second, period)
+	period, err = cyclePeriod(palette, -4)
+	require.NoError(t, err)
+	require.Equal(t, time.Second, period)
+	for _, palette := range []color.Palette{nil, {color.RGBA{A: 255}}} {
+		_, err := cyclePeriod(palette, 1)
+		require.Error(t, err, "palette of %d entries", len(palette))
+	}
+	_, err = cyclePeriod(palette, 0)
+	require.Error(t, err)
+}
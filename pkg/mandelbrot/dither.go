@@ -0,0 +1,304 @@
+package mandelbrot
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"time"
+)
+
+const (
+	// DitherNone maps every pixel to the nearest palette color.
+	DitherNone = "none"
+	// DitherFloydSteinberg diffuses the quantization error of every pixel over its unprocessed neighbours.
+	// Its pattern depends on all pixels before it, so it is only stable where the source pixels are identical
+	// to those of the previous frame. It suits still or palette cycling views; when the camera moves,
+	// the pattern crawls across the whole frame, so use DitherBayer for those.
+	DitherFloydSteinberg = "floyd-steinberg"
+	// DitherBayer adds an 8x8 ordered threshold pattern, anchored to the pixel grid, before picking the nearest color.
+	// Every pixel is dithered on its own, so a small change only affects the pixels close to a threshold,
+	// which makes it the stable choice for animations.
+	DitherBayer = "bayer"
+)
+
+const (
+	// GIFPaletteGlobal quantizes every frame to the palette the GIF sink was created with.
+	GIFPaletteGlobal = "global"
+	// GIFPaletteAdaptive quantizes every frame to its own palette,
+	// refined from the palette of the previous frame so that colors do not jump between frames.
+	GIFPaletteAdaptive = "adaptive"
+)
+
+const (
+	adaptiveMaxSamples = 1 << 12
+	adaptiveIterations = 4
+	// nearestCacheSize caps the number of colors for which the nearest palette entry is remembered.
+	nearestCacheSize = 1 << 16
+	// gifPaletteWidth is the width of the stills sampled by SampleGIFPalette.
+	gifPaletteWidth = 96
+)
+
+// bayerMatrix is the 8x8 ordered dithering threshold map, with values 0 to 63.
+var bayerMatrix = [8][8]float64{
+	{0, 32, 8, 40, 2, 34, 10, 42},
+	{48, 16, 56, 24, 50, 18, 58, 26},
+	{12, 44, 4, 36, 14, 46, 6, 38},
+	{60, 28, 52, 20, 62, 30, 54, 22},
+	{3, 35, 11, 43, 1, 33, 9, 41},
+	{51, 19, 59, 27, 49, 17, 57, 25},
+	{15, 47, 7, 39, 13, 45, 5, 37},
+	{63, 31, 55, 23, 61, 29, 53, 21},
+}
+
+// SampleGIFPalette chooses a palette of 256 colors for the whole animation,
+// from small stills rendered at evenly spaced positions along the timeline.
+func SampleGIFPalette(cfg AnimationConfig, palette color.Palette, stills int) (color.Palette, error) {
+	if stills <= 0 {
+		return nil, fmt.Errorf("invalid number of stills (%d)", stills)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("validating animation config: %w", err)
+	}
+	small := cfg
+	if small.Width > gifPaletteWidth {
+		small.Width = gifPaletteWidth
+		small.Height = int(math.Max(1, math.Round(float64(cfg.Height*gifPaletteWidth)/float64(cfg.Width))))
+	}
+	a, err := newAnimator(small, palette, true)
+	if err != nil {
+		return nil, err
+	}
+	var samples []colorVec
+	for i := 0; i < stills; i++ {
+		at := time.Duration(0)
+		if stills > 1 {
+			at = a.duration * time.Duration(i) / time.Duration(stills-1)
+		}
+		img, err := a.still(at)
+		if err != nil {
+			return nil, fmt.Errorf("rendering still at (%v): %w", at, err)
+		}
+		samples = append(samples, extractSamples(img, extractMaxSamples/stills)...)
+	}
+	centers := kMeans(samples, medianCut(samples, 256), kMeansIterations)
+	sampled := make(color.Palette, len(centers))
+	for i, c := range centers {
+		sampled[i] = fromColorSpace(ColorSpaceOKLab, c, 1)
+	}
+	return sampled, nil
+}
+
+// gifQuantizer converts true color frames to paletted ones.
+// It remembers the previous frame: a pixel that did not change keeps the color it was shown in,
+// if the palette still has it, so that dithering patterns do not crawl across still parts of the animation.
+type gifQuantizer struct {
+	dither      string
+	paletteMode string
+	base        color.Palette
+	// centers are the OKLab colors of the adaptive palette.
+	centers []colorVec
+	palette color.Palette
+	colors  []color.RGBA
+	spread  float64
+	nearest map[color.RGBA]uint8
+	prevSrc *image.RGBA
+	prevOut *image.Paletted
+}
+
+func newGIFQuantizer(dither, paletteMode string, base color.Palette) (*gifQuantizer, error) {
+	switch dither {
+	case "", DitherNone, DitherFloydSteinberg, DitherBayer:
+	default:
+		return nil, fmt.Errorf("unknown dither (%s)", dither)
+	}
+	switch paletteMode {
+	case "", GIFPaletteGlobal, GIFPaletteAdaptive:
+	default:
+		return nil, fmt.Errorf("unknown palette mode (%s)", paletteMode)
+	}
+	if len(base) == 0 || len(base) > 256 {
+		return nil, fmt.Errorf("invalid palette size (%d)", len(base))
+	}
+	return &gifQuantizer{
+		dither:      dither,
+		paletteMode: paletteMode,
+		base:        base,
+	}, nil
+}
+
+func (q *gifQuantizer) Quantize(img image.Image) *image.Paletted {
+	src, ok := img.(*image.RGBA)
+	if !ok {
+		src = image.NewRGBA(img.Bounds())
+		draw.Draw(src, src.Rect, img, img.Bounds().Min, draw.Src)
+	}
+	q.setPalette(q.framePalette(src))
+	dst := image.NewPaletted(src.Rect, q.palette)
+	if q.dither == DitherFloydSteinberg {
+		q.diffuse(src, dst)
+	} else {
+		q.ordered(src, dst)
+	}
+	q.prevSrc = src
+	q.prevOut = dst
+	return dst
+}
+
+func (q *gifQuantizer) framePalette(src *image.RGBA) color.Palette {
+	if q.paletteMode != GIFPaletteAdaptive {
+		return q.base
+	}
+	if q.centers == nil {
+		q.centers = make([]colorVec, len(q.base))
+		for i, c := range q.base {
+			q.centers[i], _ = toColorSpace(ColorSpaceOKLab, c)
+		}
+	}
+	q.centers = kMeans(extractSamples(src, adaptiveMaxSamples), q.centers, adaptiveIterations)
+	palette := make(color.Palette, len(q.centers))
+	for i, c := range q.centers {
+		palette[i] = fromColorSpace(ColorSpaceOKLab, c, 1)
+	}
+	return palette
+}
+
+func (q *gifQuantizer) setPalette(palette color.Palette) {
+	if q.palette != nil && samePalette(palette, q.palette) {
+		return
+	}
+	q.palette = palette
+	q.colors = make([]color.RGBA, len(palette))
+	for i, c := range palette {
+		q.colors[i] = color.RGBAModel.Convert(c).(color.RGBA)
+	}
+	q.spread = paletteSpread(q.colors)
+	q.nearest = make(map[color.RGBA]uint8)
+}
+
+// ordered quantizes every pixel on its own, after adding the Bayer threshold if dithering is enabled.
+// The threshold only depends on the position of the pixel, so unchanged pixels keep their color.
+func (q *gifQuantizer) ordered(src *image.RGBA, dst *image.Paletted) {
+	b := src.Rect
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if idx, ok := q.previous(src, x, y); ok {
+				dst.SetColorIndex(x, y, idx)
+				continue
+			}
+			c := src.RGBAAt(x, y)
+			if q.dither != DitherBayer {
+				dst.SetColorIndex(x, y, q.index(c))
+				continue
+			}
+			offset := ((bayerMatrix[y&7][x&7]+0.5)/64 - 0.5) * q.spread
+			dst.SetColorIndex(x, y, q.index(color.RGBA{
+				R: clampByte(float64(c.R) + offset),
+				G: clampByte(float64(c.G) + offset),
+				B: clampByte(float64(c.B) + offset),
+				A: 0xff,
+			}))
+		}
+	}
+}
+
+// diffuse quantizes with Floyd–Steinberg error diffusion.
+// Pixels that keep their previous color neither receive nor spread any error,
+// so that a change in one part of the frame does not ripple through the rest of it.
+func (q *gifQuantizer) diffuse(src *image.RGBA, dst *image.Paletted) {
+	b := src.Rect
+	// The error rows have a pixel of padding on either side.
+	current := make([][3]float64, b.Dx()+2)
+	next := make([][3]float64, b.Dx()+2)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			i := x - b.Min.X + 1
+			if idx, ok := q.previous(src, x, y); ok {
+				dst.SetColorIndex(x, y, idx)
+				continue
+			}
+			c := src.RGBAAt(x, y)
+			want := [3]float64{
+				float64(c.R) + current[i][0],
+				float64(c.G) + current[i][1],
+				float64(c.B) + current[i][2],
+			}
+			idx := q.index(color.RGBA{R: clampByte(want[0]), G: clampByte(want[1]), B: clampByte(want[2]), A: 0xff})
+			dst.SetColorIndex(x, y, idx)
+			got := q.colors[idx]
+			for ch, v := range [3]uint8{got.R, got.G, got.B} {
+				e := want[ch] - float64(v)
+				current[i+1][ch] += e * 7 / 16
+				next[i-1][ch] += e * 3 / 16
+				next[i][ch] += e * 5 / 16
+				next[i+1][ch] += e * 1 / 16
+			}
+		}
+		current, next = next, current
+		for i := range next {
+			next[i] = [3]float64{}
+		}
+	}
+}
+
+// previous returns the index of the color the pixel had in the previous frame,
+// if neither the pixel nor that color in the palette changed.
+func (q *gifQuantizer) previous(src *image.RGBA, x, y int) (uint8, bool) {
+	if q.prevSrc == nil || q.prevSrc.Rect != src.Rect || q.prevSrc.RGBAAt(x, y) != src.RGBAAt(x, y) {
+		return 0, false
+	}
+	shown := color.RGBAModel.Convert(q.prevOut.At(x, y)).(color.RGBA)
+	idx := q.index(shown)
+	return idx, q.colors[idx] == shown
+}
+
+// index returns the palette entry nearest to c.
+func (q *gifQuantizer) index(c color.RGBA) uint8 {
+	c.A = 0xff
+	if idx, ok := q.nearest[c]; ok {
+		return idx
+	}
+	nearest, nearestDist := 0, math.MaxInt
+	for i, p := range q.colors {
+		dr, dg, db := int(c.R)-int(p.R), int(c.G)-int(p.G), int(c.B)-int(p.B)
+		if d := dr*dr + dg*dg + db*db; d < nearestDist {
+			nearest, nearestDist = i, d
+		}
+	}
+	if len(q.nearest) >= nearestCacheSize {
+		q.nearest = make(map[color.RGBA]uint8)
+	}
+	q.nearest[c] = uint8(nearest)
+	return uint8(nearest)
+}
+
+// paletteSpread is the average distance from every color to the nearest other color, per channel,
+// which is the amplitude ordered dithering needs to reach the neighbouring colors.
+func paletteSpread(colors []color.RGBA) float64 {
+	var total float64
+	var n int
+	for i, a := range colors {
+		nearest := math.Inf(1)
+		for j, b := range colors {
+			if i == j || a == b {
+				continue
+			}
+			dr, dg, db := float64(a.R)-float64(b.R), float64(a.G)-float64(b.G), float64(a.B)-float64(b.B)
+			nearest = math.Min(nearest, dr*dr+dg*dg+db*db)
+		}
+		if !math.IsInf(nearest, 1) {
+			total += math.Sqrt(nearest)
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	// The offset is added to all three channels, which moves the color by sqrt(3) times as much.
+	return total / float64(n) / math.Sqrt(3)
+}
+
+func clampByte(v float64) uint8 {
+	return uint8(math.Max(0, math.Min(255, math.Round(v))))
+}
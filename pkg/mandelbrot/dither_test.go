@@ -0,0 +1,174 @@
+package mandelbrot
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+	"time"
+)
+
+// grayRamp is a horizontal ramp from black to white.
+func grayRamp(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := uint8(x * 255 / (width - 1))
+			img.SetRGBA(x, y, color.RGBA{R: v, G: v, B: v, A: 255})
+		}
+	}
+	return img
+}
+
+// meanGray returns the average displayed brightness of the columns from x0 up to x1.
+func meanGray(img image.Image, x0, x1 int) float64 {
+	var total float64
+	var n int
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := x0; x < x1; x++ {
+			total += float64(rgba(img.At(x, y)).R)
+			n++
+		}
+	}
+	return total / float64(n)
+}
+
+func TestGIFQuantizerDither(t *testing.T) {
+	blackAndWhite := color.Palette{color.RGBA{A: 255}, color.RGBA{R: 255, G: 255, B: 255, A: 255}}
+	for _, test := range []struct {
+		dither    string
+		tolerance float64
+	}{
+		{dither: DitherFloydSteinberg, tolerance: 8},
+		{dither: DitherBayer, tolerance: 8},
+	} {
+		t.Run(test.dither, func(t *testing.T) {
+			q, err := newGIFQuantizer(test.dither, GIFPaletteGlobal, blackAndWhite)
+			require.NoError(t, err)
+			src := grayRamp(64, 16)
+			dst := q.Quantize(src)
+			for x0 := 0; x0 < 64; x0 += 16 {
+				require.InDelta(t, meanGray(src, x0, x0+16), meanGray(dst, x0, x0+16), test.tolerance, "columns %d-%d", x0, x0+15)
+			}
+		})
+	}
+}
+
+func TestGIFQuantizerStable(t *testing.T) {
+	palette := Gradient(color.RGBA{A: 255}, color.RGBA{R: 255, G: 255, B: 255, A: 255}, 16)
+	changed := image.Rect(20, 4, 28, 10)
+	for _, dither := range []string{DitherNone, DitherFloydSteinberg, DitherBayer} {
+		for _, mode := range []string{GIFPaletteGlobal, GIFPaletteAdaptive} {
+			t.Run(dither+"/"+mode, func(t *testing.T) {
+				q, err := newGIFQuantizer(dither, mode, palette)
+				require.NoError(t, err)
+				first := q.Quantize(grayRamp(48, 16))
+				src := grayRamp(48, 16)
+				for y := changed.Min.Y; y < changed.Max.Y; y++ {
+					for x := changed.Min.X; x < changed.Max.X; x++ {
+						src.SetRGBA(x, y, color.RGBA{R: 200, G: 30, B: 30, A: 255})
+					}
+				}
+				second := q.Quantize(src)
+				var kept, total int
+				for y := 0; y < 16; y++ {
+					for x := 0; x < 48; x++ {
+						if image.Pt(x, y).In(changed) {
+							continue
+						}
+						total++
+						if rgba(first.At(x, y)) == rgba(second.At(x, y)) {
+							kept++
+						}
+					}
+				}
+				if mode == GIFPaletteGlobal {
+					require.Equal(t, total, kept)
+				} else {
+					// Adapting the palette to the new color moves the entries around it,
+					// taking away colors some of the unchanged pixels used.
+					require.Greater(t, kept, total/2)
+				}
+			})
+		}
+	}
+}
+
+func TestGIFQuantizerShifted(t *testing.T) {
+	palette := Gradient(color.RGBA{A: 255}, color.RGBA{R: 255, G: 255, B: 255, A: 255}, 16)
+	// A diagonal ramp, moved one pixel to the left: every pixel changes a little, as when the camera pans.
+	ramp := func(shift int) *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, 128, 64))
+		for y := 0; y < 64; y++ {
+			for x := 0; x < 128; x++ {
+				v := uint8((x + shift + y) * 255 / (128 + 64))
+				img.SetRGBA(x, y, color.RGBA{R: v, G: v, B: v, A: 255})
+			}
+		}
+		return img
+	}
+	changed := make(map[string]int)
+	for _, dither := range []string{DitherFloydSteinberg, DitherBayer} {
+		q, err := newGIFQuantizer(dither, GIFPaletteGlobal, palette)
+		require.NoError(t, err)
+		first := q.Quantize(ramp(0))
+		second := q.Quantize(ramp(1))
+		for i := range first.Pix {
+			if first.Pix[i] != second.Pix[i] {
+				changed[dither]++
+			}
+		}
+	}
+	// The Bayer pattern stays in place, so only pixels close to a threshold change.
+	require.Less(t, changed[DitherBayer], 128*64/10)
+	// Error diffusion spreads every small change over the rest of the frame.
+	require.Greater(t, changed[DitherFloydSteinberg], 2*changed[DitherBayer])
+}
+
+func TestGIFSinkDither(t *testing.T) {
+	palette := Gradient(color.RGBA{A: 255}, color.RGBA{R: 255, G: 255, B: 255, A: 255}, 2)
+	var buf bytes.Buffer
+	sink := NewGIFSink(&buf, 32, 8, palette)
+	require.False(t, sink.TrueColor())
+	sink.Dither = DitherBayer
+	sink.PaletteMode = GIFPaletteAdaptive
+	require.True(t, sink.TrueColor())
+	red := image.NewRGBA(image.Rect(0, 0, 32, 8))
+	for i := 0; i < len(red.Pix); i += 4 {
+		copy(red.Pix[i:], []uint8{255, 0, 0, 255})
+	}
+	for i, img := range []image.Image{grayRamp(32, 8), red} {
+		require.NoError(t, sink.WriteFrame(Frame{Index: i, Delay: 100 * time.Millisecond, Image: img}))
+	}
+	require.NoError(t, sink.Close())
+
+	g, err := gif.DecodeAll(&buf)
+	require.NoError(t, err)
+	require.Len(t, g.Image, 2)
+	// The adaptive palette has moved one of its colors to red.
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 32; x++ {
+			require.Equal(t, color.RGBA{R: 255, A: 255}, rgba(g.Image[1].At(x, y)), "pixel (%d,%d)", x, y)
+		}
+	}
+}
+
+func TestSampleGIFPalette(t *testing.T) {
+	cfg := AnimationConfig{
+		Width:         64,
+		Height:        48,
+		FPS:           10,
+		MaxIterations: 50,
+		Path: []AnimationConfigPathElement{
+			{Zoom: 1, TargetX: 0.5, TargetY: 0.5},
+			{Zoom: 4, TargetX: 0.3, TargetY: 0.5, Duration: time.Second},
+		},
+	}
+	palette, err := SampleGIFPalette(cfg, DefaultPalette(), 4)
+	require.NoError(t, err)
+	require.NotEmpty(t, palette)
+	require.LessOrEqual(t, len(palette), 256)
+}
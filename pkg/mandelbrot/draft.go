@@ -0,0 +1,100 @@
+package mandelbrot
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+const (
+	contactSheetMargin = 4
+	// contactSheetColumns is the maximum number of thumbnails per row.
+	contactSheetColumns = 4
+)
+
+// Draft returns a copy of the config that renders quickly, for checking the timeline before a full render.
+// Resolution, FPS and iteration counts are multiplied by scale, which must be in (0, 1]; motion blur is disabled.
+func (cfg AnimationConfig) Draft(scale float64) (AnimationConfig, error) {
+	if scale <= 0 || scale > 1 {
+		return AnimationConfig{}, fmt.Errorf("invalid draft scale (%f)", scale)
+	}
+	scaled := func(v int) int {
+		return int(math.Max(1, math.Round(float64(v)*scale)))
+	}
+	draft := cfg
+	draft.Width = scaled(cfg.Width)
+	draft.Height = scaled(cfg.Height)
+	draft.FPS = scaled(cfg.FPS)
+	draft.MaxIterations = scaled(cfg.MaxIterations)
+	if cfg.MaxIterationsLimit > 0 {
+		draft.MaxIterationsLimit = scaled(cfg.MaxIterationsLimit)
+	}
+	draft.MotionBlurSamples = 0
+	draft.Path = make([]AnimationConfigPathElement, len(cfg.Path))
+	for i, pe := range cfg.Path {
+		if pe.MaxIterations > 0 {
+			pe.MaxIterations = scaled(pe.MaxIterations)
+		}
+		draft.Path[i] = pe
+	}
+	return draft, nil
+}
+
+// ContactSheet renders every element of the Path as a thumbnail of the configured size,
+// labelled with its index and coordinates, in a grid.
+func ContactSheet(cfg AnimationConfig, palette color.Palette) (*image.RGBA, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("validating animation config: %w", err)
+	}
+	columns := contactSheetColumns
+	if len(cfg.Path) < columns {
+		columns = len(cfg.Path)
+	}
+	rows := (len(cfg.Path) + columns - 1) / columns
+	labels := make([]string, len(cfg.Path))
+	labelHeight := 0
+	cellWidth := cfg.Width
+	for i, pe := range cfg.Path {
+		labels[i] = fmt.Sprintf("#%d ZOOM %.6g\nX %.6g\nY %.6g", i, pe.Zoom, pe.TargetX, pe.TargetY)
+		size := textSize(labels[i], 1)
+		if size.Y > labelHeight {
+			labelHeight = size.Y
+		}
+		if size.X > cellWidth {
+			cellWidth = size.X
+		}
+	}
+	cellHeight := cfg.Height + contactSheetMargin + labelHeight
+	sheet := image.NewRGBA(image.Rect(0, 0,
+		columns*(cellWidth+contactSheetMargin)+contactSheetMargin,
+		rows*(cellHeight+contactSheetMargin)+contactSheetMargin,
+	))
+	draw.Draw(sheet, sheet.Rect, image.NewUniform(color.Black), image.Point{}, draw.Src)
+	palette, err := cfg.BasePalette(palette)
+	if err != nil {
+		return nil, err
+	}
+	path, err := cfg.resolvedPath(palette)
+	if err != nil {
+		return nil, fmt.Errorf("creating palettes: %w", err)
+	}
+	thumbnail := image.NewRGBA(image.Rect(0, 0, cfg.Width, cfg.Height))
+	for i, pe := range path {
+		renderCfg, err := cfg.renderConfig(keyframeState(pe))
+		if err != nil {
+			return nil, fmt.Errorf("determining render config (path element %d): %w", i, err)
+		}
+		if err := RenderRGBA(renderCfg, thumbnail, pe.palette); err != nil {
+			return nil, fmt.Errorf("rendering (path element %d): %w", i, err)
+		}
+		origin := image.Pt(
+			contactSheetMargin+(i%columns)*(cellWidth+contactSheetMargin),
+			contactSheetMargin+(i/columns)*(cellHeight+contactSheetMargin),
+		)
+		draw.Draw(sheet, thumbnail.Rect.Add(origin), thumbnail, image.Point{}, draw.Src)
+		drawText(sheet, origin.Add(image.Pt(0, cfg.Height+contactSheetMargin)), labels[i], color.White, 1)
+	}
+	return sheet, nil
+}
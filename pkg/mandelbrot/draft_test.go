@@ -0,0 +1,57 @@
+package mandelbrot
+
+import (
+	"github.com/stretchr/testify/require"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestDraft(t *testing.T) {
+	cfg := AnimationConfig{
+		Width:             640,
+		Height:            480,
+		FPS:               30,
+		MaxIterations:     200,
+		MotionBlurSamples: 4,
+		Path: []AnimationConfigPathElement{
+			{Zoom: 1, TargetX: 0.5, TargetY: 0.5},
+			{Zoom: 4, TargetX: 0.4, TargetY: 0.4, MaxIterations: 400, Duration: time.Second},
+		},
+	}
+	draft, err := cfg.Draft(0.25)
+	require.NoError(t, err)
+	require.Equal(t, 160, draft.Width)
+	require.Equal(t, 120, draft.Height)
+	require.Equal(t, 8, draft.FPS)
+	require.Equal(t, 50, draft.MaxIterations)
+	require.Equal(t, 0, draft.MotionBlurSamples)
+	require.Equal(t, 0, draft.Path[0].MaxIterations)
+	require.Equal(t, 100, draft.Path[1].MaxIterations)
+	require.Equal(t, 400, cfg.Path[1].MaxIterations, "original config must be unchanged")
+
+	_, err = cfg.Draft(0)
+	require.Error(t, err)
+}
+
+func TestContactSheet(t *testing.T) {
+	cfg := AnimationConfig{
+		Width:         32,
+		Height:        24,
+		FPS:           10,
+		MaxIterations: 50,
+		Path: []AnimationConfigPathElement{
+			{Zoom: 1, TargetX: 0.5, TargetY: 0.5},
+			{Zoom: 4, TargetX: 0.4, TargetY: 0.4, Duration: time.Second},
+			{Zoom: 8, TargetX: 0.4, TargetY: 0.4, Duration: time.Second},
+		},
+	}
+	palette := Gradient(color.RGBA{B: 255, A: 255}, color.Black, 16)
+	sheet, err := ContactSheet(cfg, palette)
+	require.NoError(t, err)
+	// Three cells in a single row, each wide enough for its label.
+	labelSize := textSize("#0 ZOOM 1", 1)
+	require.Equal(t, 3*(labelSize.X+contactSheetMargin)+contactSheetMargin, sheet.Rect.Dx())
+	labelHeight := 3*lineAdvance - 2
+	require.Equal(t, cfg.Height+labelHeight+3*contactSheetMargin, sheet.Rect.Dy())
+}
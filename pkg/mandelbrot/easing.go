@@ -0,0 +1,120 @@
+package mandelbrot
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Easing maps a linear progress fraction between 0 and 1 onto an eased fraction.
+// Easing functions must return 0 for 0 and 1 for 1.
+type Easing func(t float64) float64
+
+func EaseLinear(t float64) float64 {
+	return t
+}
+
+// EaseHold keeps the start value for the entire segment, and only jumps to the end value at the very end.
+func EaseHold(t float64) float64 {
+	if t >= 1 {
+		return 1
+	}
+	return 0
+}
+
+var (
+	EaseIn    = CubicBezier(0.42, 0, 1, 1)
+	EaseOut   = CubicBezier(0, 0, 0.58, 1)
+	EaseInOut = CubicBezier(0.42, 0, 0.58, 1)
+)
+
+// CubicBezier returns an easing curve defined by two control points,
+// with the same semantics as the CSS cubic-bezier() timing function.
+// The curve starts at (0,0) and ends at (1,1).
+func CubicBezier(x1, y1, x2, y2 float64) Easing {
+	bezier := func(t, p1, p2 float64) float64 {
+		it := 1 - t
+		return 3*it*it*t*p1 + 3*it*t*t*p2 + t*t*t
+	}
+	bezierSlope := func(t, p1, p2 float64) float64 {
+		it := 1 - t
+		return 3*it*it*p1 + 6*it*t*(p2-p1) + 3*t*t*(1-p2)
+	}
+	return func(x float64) float64 {
+		if x <= 0 {
+			return 0
+		}
+		if x >= 1 {
+			return 1
+		}
+		// Newton's method converges quickly on well-behaved curves.
+		t := x
+		for i := 0; i < 8; i++ {
+			diff := bezier(t, x1, x2) - x
+			if math.Abs(diff) < 1e-9 {
+				return bezier(t, y1, y2)
+			}
+			slope := bezierSlope(t, x1, x2)
+			if math.Abs(slope) < 1e-9 {
+				break
+			}
+			t -= diff / slope
+		}
+		// Fall back on bisection when Newton's method does not converge.
+		lo, hi := 0.0, 1.0
+		t = x
+		for i := 0; i < 64; i++ {
+			cx := bezier(t, x1, x2)
+			if math.Abs(cx-x) < 1e-9 {
+				break
+			}
+			if cx < x {
+				lo = t
+			} else {
+				hi = t
+			}
+			t = (lo + hi) / 2
+		}
+		return bezier(t, y1, y2)
+	}
+}
+
+// ParseEasing parses an easing specification as found in the animation config.
+// Supported are "linear", "ease-in", "ease-out", "ease-in-out", "hold",
+// and "cubic-bezier(x1,y1,x2,y2)". The empty string is equivalent to "linear".
+func ParseEasing(spec string) (Easing, error) {
+	spec = strings.TrimSpace(strings.ToLower(spec))
+	switch spec {
+	case "", "linear":
+		return EaseLinear, nil
+	case "ease-in":
+		return EaseIn, nil
+	case "ease-out":
+		return EaseOut, nil
+	case "ease-in-out":
+		return EaseInOut, nil
+	case "hold":
+		return EaseHold, nil
+	}
+	const bezierPrefix = "cubic-bezier("
+	if !strings.HasPrefix(spec, bezierPrefix) || !strings.HasSuffix(spec, ")") {
+		return nil, fmt.Errorf("unknown easing (%s)", spec)
+	}
+	rawPoints := strings.Split(strings.TrimSuffix(strings.TrimPrefix(spec, bezierPrefix), ")"), ",")
+	if len(rawPoints) != 4 {
+		return nil, fmt.Errorf("cubic-bezier requires 4 values, got %d", len(rawPoints))
+	}
+	var points [4]float64
+	for i, rawPoint := range rawPoints {
+		p, err := strconv.ParseFloat(strings.TrimSpace(rawPoint), 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing cubic-bezier value (%s): %w", rawPoint, err)
+		}
+		points[i] = p
+	}
+	if points[0] < 0 || points[0] > 1 || points[2] < 0 || points[2] > 1 {
+		return nil, fmt.Errorf("cubic-bezier x values (%f,%f) must be between 0 and 1", points[0], points[2])
+	}
+	return CubicBezier(points[0], points[1], points[2], points[3]), nil
+}
@@ -0,0 +1,56 @@
+package mandelbrot
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseEasing(t *testing.T) {
+	var tests = []struct {
+		desc   string
+		spec   string
+		at     []float64
+		result []float64
+	}{
+		{
+			desc:   "linear",
+			spec:   "",
+			at:     []float64{0, 0.25, 1},
+			result: []float64{0, 0.25, 1},
+		},
+		{
+			desc:   "hold",
+			spec:   "hold",
+			at:     []float64{0, 0.5, 0.99, 1},
+			result: []float64{0, 0, 0, 1},
+		},
+		{
+			desc:   "ease-in-out",
+			spec:   "ease-in-out",
+			at:     []float64{0, 0.5, 1},
+			result: []float64{0, 0.5, 1},
+		},
+		{
+			desc:   "linear bezier",
+			spec:   "cubic-bezier(0.25, 0.25, 0.75, 0.75)",
+			at:     []float64{0, 0.1, 0.3, 0.8, 1},
+			result: []float64{0, 0.1, 0.3, 0.8, 1},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			easing, err := ParseEasing(test.spec)
+			require.NoError(t, err)
+			for i, at := range test.at {
+				require.InDelta(t, test.result[i], easing(at), 1e-6)
+			}
+		})
+	}
+}
+
+func TestParseEasingInvalid(t *testing.T) {
+	for _, spec := range []string{"bounce", "cubic-bezier(1,2,3)", "cubic-bezier(2,0,0.5,1)", "cubic-bezier(a,0,0.5,1)"} {
+		_, err := ParseEasing(spec)
+		require.Error(t, err, spec)
+	}
+}
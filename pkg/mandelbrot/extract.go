@@ -0,0 +1,215 @@
+package mandelbrot
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+const (
+	// ExtractMedianCut repeatedly splits the box of colors with the largest extent at its median.
+	ExtractMedianCut = "median-cut"
+	// ExtractKMeans refines the median cut colors with k-means clustering.
+	ExtractKMeans = "k-means"
+)
+
+const (
+	// extractMaxSamples caps the number of pixels considered, by skipping pixels in large images.
+	extractMaxSamples = 1 << 16
+	kMeansIterations  = 20
+)
+
+// ExtractPalette quantizes the image to at most n colors, working in OKLab.
+// The colors are ordered into a smooth path, starting with the darkest color and moving on to the nearest one,
+// so that the result can be used as a gradient.
+func ExtractPalette(img image.Image, n int, method string) (color.Palette, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid number of colors (%d)", n)
+	}
+	samples := extractSamples(img, extractMaxSamples)
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("image has no opaque pixels")
+	}
+	var centers []colorVec
+	switch method {
+	case ExtractMedianCut:
+		centers = medianCut(samples, n)
+	case ExtractKMeans:
+		centers = kMeans(samples, medianCut(samples, n), kMeansIterations)
+	default:
+		return nil, fmt.Errorf("unknown extraction method (%s)", method)
+	}
+	centers = nearestNeighbourPath(centers)
+	palette := make(color.Palette, len(centers))
+	for i, c := range centers {
+		palette[i] = fromColorSpace(ColorSpaceOKLab, c, 1)
+	}
+	return palette, nil
+}
+
+// PaletteConfigFromColors creates a palette config with the colors as evenly spaced stops,
+// so that an extracted palette can be saved in the animation config.
+func PaletteConfigFromColors(colors color.Palette) PaletteConfig {
+	pc := PaletteConfig{ColorSpace: ColorSpaceOKLab}
+	for i, c := range colors {
+		position := 0.0
+		if len(colors) > 1 {
+			position = float64(i) / float64(len(colors)-1)
+		}
+		pc.Stops = append(pc.Stops, PaletteStop{Position: position, Color: HexColor(c)})
+	}
+	return pc
+}
+
+// extractSamples converts the opaque pixels of the image to OKLab.
+// Pixels are skipped in large images, to return at most about maxSamples samples.
+func extractSamples(img image.Image, maxSamples int) []colorVec {
+	b := img.Bounds()
+	step := 1
+	for (b.Dx()/step)*(b.Dy()/step) > maxSamples {
+		step++
+	}
+	var samples []colorVec
+	for y := b.Min.Y; y < b.Max.Y; y += step {
+		for x := b.Min.X; x < b.Max.X; x += step {
+			c := img.At(x, y)
+			if _, _, _, a := c.RGBA(); a < 0x8000 {
+				continue
+			}
+			v, _ := toColorSpace(ColorSpaceOKLab, c)
+			samples = append(samples, v)
+		}
+	}
+	return samples
+}
+
+// medianCut splits the samples into at most n boxes, and returns the mean of each box.
+func medianCut(samples []colorVec, n int) []colorVec {
+	boxes := [][]colorVec{append([]colorVec(nil), samples...)}
+	for len(boxes) < n {
+		// Split the box with the largest extent along any axis.
+		best, bestAxis, bestExtent := -1, 0, 0.0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			axis, extent := widestAxis(box)
+			if extent > bestExtent {
+				best, bestAxis, bestExtent = i, axis, extent
+			}
+		}
+		if best == -1 {
+			break
+		}
+		box := boxes[best]
+		sort.Slice(box, func(i, j int) bool { return box[i][bestAxis] < box[j][bestAxis] })
+		median := len(box) / 2
+		boxes[best] = box[:median]
+		boxes = append(boxes, box[median:])
+	}
+	centers := make([]colorVec, len(boxes))
+	for i, box := range boxes {
+		centers[i] = meanColor(box)
+	}
+	return centers
+}
+
+func widestAxis(box []colorVec) (int, float64) {
+	axis, extent := 0, 0.0
+	for a := 0; a < 3; a++ {
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for _, v := range box {
+			lo = math.Min(lo, v[a])
+			hi = math.Max(hi, v[a])
+		}
+		if hi-lo > extent {
+			axis, extent = a, hi-lo
+		}
+	}
+	return axis, extent
+}
+
+func meanColor(vs []colorVec) colorVec {
+	var sum colorVec
+	for _, v := range vs {
+		for a := range sum {
+			sum[a] += v[a]
+		}
+	}
+	for a := range sum {
+		sum[a] /= float64(len(vs))
+	}
+	return sum
+}
+
+// kMeans moves the centers to the mean of the samples nearest to them, until they settle or the iterations run out.
+// A center without any samples keeps its position.
+func kMeans(samples []colorVec, centers []colorVec, iterations int) []colorVec {
+	assignment := make([]int, len(samples))
+	for iteration := 0; iteration < iterations; iteration++ {
+		changed := iteration == 0
+		for i, s := range samples {
+			nearest := nearestColor(centers, s)
+			if nearest != assignment[i] {
+				assignment[i] = nearest
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+		clusters := make([][]colorVec, len(centers))
+		for i, s := range samples {
+			clusters[assignment[i]] = append(clusters[assignment[i]], s)
+		}
+		for i, cluster := range clusters {
+			if len(cluster) > 0 {
+				centers[i] = meanColor(cluster)
+			}
+		}
+	}
+	return centers
+}
+
+func nearestColor(centers []colorVec, v colorVec) int {
+	nearest, nearestDist := 0, math.Inf(1)
+	for i, c := range centers {
+		if d := colorDistance(c, v); d < nearestDist {
+			nearest, nearestDist = i, d
+		}
+	}
+	return nearest
+}
+
+// colorDistance is the squared euclidean distance, which in OKLab approximates perceived difference.
+func colorDistance(a, b colorVec) float64 {
+	var d float64
+	for i := range a {
+		d += (a[i] - b[i]) * (a[i] - b[i])
+	}
+	return d
+}
+
+// nearestNeighbourPath orders the colors by starting at the darkest, and repeatedly moving to the nearest unvisited color.
+func nearestNeighbourPath(colors []colorVec) []colorVec {
+	remaining := append([]colorVec(nil), colors...)
+	darkest := 0
+	for i, c := range remaining {
+		if c[0] < remaining[darkest][0] {
+			darkest = i
+		}
+	}
+	path := make([]colorVec, 0, len(colors))
+	current := remaining[darkest]
+	remaining = append(remaining[:darkest], remaining[darkest+1:]...)
+	path = append(path, current)
+	for len(remaining) > 0 {
+		next := nearestColor(remaining, current)
+		current = remaining[next]
+		remaining = append(remaining[:next], remaining[next+1:]...)
+		path = append(path, current)
+	}
+	return path
+}
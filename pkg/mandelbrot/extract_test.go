@@ -0,0 +1,41 @@
+package mandelbrot
+
+import (
+	"github.com/stretchr/testify/require"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestExtractPalette(t *testing.T) {
+	// Four flat bands, in an order that is not a smooth path.
+	bands := []color.RGBA{
+		{R: 255, G: 255, B: 255, A: 255},
+		{A: 255},
+		{R: 200, G: 200, B: 200, A: 255},
+		{R: 60, G: 60, B: 60, A: 255},
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 40, 10))
+	for i, c := range bands {
+		draw.Draw(img, image.Rect(i*10, 0, (i+1)*10, 10), image.NewUniform(c), image.Point{}, draw.Src)
+	}
+	for _, method := range []string{ExtractMedianCut, ExtractKMeans} {
+		t.Run(method, func(t *testing.T) {
+			palette, err := ExtractPalette(img, 4, method)
+			require.NoError(t, err)
+			// Ordered from the darkest color, along the nearest neighbours.
+			require.Equal(t, color.Palette{bands[1], bands[3], bands[2], bands[0]}, palette)
+
+			palette, err = ExtractPalette(img, 8, method)
+			require.NoError(t, err)
+			require.Len(t, palette, 4, "no more colors than the image contains")
+		})
+	}
+	_, err := ExtractPalette(img, 4, "octree")
+	require.Error(t, err)
+
+	pc := PaletteConfigFromColors(color.Palette{bands[1], bands[0]})
+	require.Equal(t, []PaletteStop{{0, "#000000"}, {1, "#ffffff"}}, pc.Stops)
+	require.NoError(t, pc.Validate())
+}
@@ -0,0 +1,123 @@
+package mandelbrot
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"unicode"
+)
+
+const (
+	glyphWidth  = 5
+	glyphHeight = 7
+	// glyphAdvance includes one column of spacing between characters.
+	glyphAdvance = glyphWidth + 1
+	// lineAdvance includes two rows of spacing between lines.
+	lineAdvance = glyphHeight + 2
+)
+
+// glyphs is a small built-in bitmap font, so that text can be drawn without external font files.
+// Lowercase letters are drawn as uppercase; characters without a glyph are drawn as '?'.
+var glyphs = map[rune][glyphHeight]string{
+	' ':  {"     ", "     ", "     ", "     ", "     ", "     ", "     "},
+	'0':  {" ### ", "#   #", "#  ##", "# # #", "##  #", "#   #", " ### "},
+	'1':  {"  #  ", " ##  ", "  #  ", "  #  ", "  #  ", "  #  ", " ### "},
+	'2':  {" ### ", "#   #", "    #", "   # ", "  #  ", " #   ", "#####"},
+	'3':  {"#####", "   # ", "  #  ", "   # ", "    #", "#   #", " ### "},
+	'4':  {"   # ", "  ## ", " # # ", "#  # ", "#####", "   # ", "   # "},
+	'5':  {"#####", "#    ", "#### ", "    #", "    #", "#   #", " ### "},
+	'6':  {"  ## ", " #   ", "#    ", "#### ", "#   #", "#   #", " ### "},
+	'7':  {"#####", "    #", "   # ", "  #  ", " #   ", " #   ", " #   "},
+	'8':  {" ### ", "#   #", "#   #", " ### ", "#   #", "#   #", " ### "},
+	'9':  {" ### ", "#   #", "#   #", " ####", "    #", "   # ", " ##  "},
+	'A':  {" ### ", "#   #", "#   #", "#####", "#   #", "#   #", "#   #"},
+	'B':  {"#### ", "#   #", "#   #", "#### ", "#   #", "#   #", "#### "},
+	'C':  {" ### ", "#   #", "#    ", "#    ", "#    ", "#   #", " ### "},
+	'D':  {"###  ", "#  # ", "#   #", "#   #", "#   #", "#  # ", "###  "},
+	'E':  {"#####", "#    ", "#    ", "#### ", "#    ", "#    ", "#####"},
+	'F':  {"#####", "#    ", "#    ", "#### ", "#    ", "#    ", "#    "},
+	'G':  {" ### ", "#   #", "#    ", "# ###", "#   #", "#   #", " ####"},
+	'H':  {"#   #", "#   #", "#   #", "#####", "#   #", "#   #", "#   #"},
+	'I':  {" ### ", "  #  ", "  #  ", "  #  ", "  #  ", "  #  ", " ### "},
+	'J':  {"  ###", "   # ", "   # ", "   # ", "   # ", "#  # ", " ##  "},
+	'K':  {"#   #", "#  # ", "# #  ", "##   ", "# #  ", "#  # ", "#   #"},
+	'L':  {"#    ", "#    ", "#    ", "#    ", "#    ", "#    ", "#####"},
+	'M':  {"#   #", "## ##", "# # #", "# # #", "#   #", "#   #", "#   #"},
+	'N':  {"#   #", "#   #", "##  #", "# # #", "#  ##", "#   #", "#   #"},
+	'O':  {" ### ", "#   #", "#   #", "#   #", "#   #", "#   #", " ### "},
+	'P':  {"#### ", "#   #", "#   #", "#### ", "#    ", "#    ", "#    "},
+	'Q':  {" ### ", "#   #", "#   #", "#   #", "# # #", "#  # ", " ## #"},
+	'R':  {"#### ", "#   #", "#   #", "#### ", "# #  ", "#  # ", "#   #"},
+	'S':  {" ####", "#    ", "#    ", " ### ", "    #", "    #", "#### "},
+	'T':  {"#####", "  #  ", "  #  ", "  #  ", "  #  ", "  #  ", "  #  "},
+	'U':  {"#   #", "#   #", "#   #", "#   #", "#   #", "#   #", " ### "},
+	'V':  {"#   #", "#   #", "#   #", "#   #", "#   #", " # # ", "  #  "},
+	'W':  {"#   #", "#   #", "#   #", "# # #", "# # #", "# # #", " # # "},
+	'X':  {"#   #", "#   #", " # # ", "  #  ", " # # ", "#   #", "#   #"},
+	'Y':  {"#   #", "#   #", " # # ", "  #  ", "  #  ", "  #  ", "  #  "},
+	'Z':  {"#####", "    #", "   # ", "  #  ", " #   ", "#    ", "#####"},
+	'.':  {"     ", "     ", "     ", "     ", "     ", " ##  ", " ##  "},
+	',':  {"     ", "     ", "     ", "     ", " ##  ", "  #  ", " #   "},
+	':':  {"     ", " ##  ", " ##  ", "     ", " ##  ", " ##  ", "     "},
+	';':  {"     ", " ##  ", " ##  ", "     ", " ##  ", "  #  ", " #   "},
+	'-':  {"     ", "     ", "     ", "#####", "     ", "     ", "     "},
+	'+':  {"     ", "  #  ", "  #  ", "#####", "  #  ", "  #  ", "     "},
+	'=':  {"     ", "     ", "#####", "     ", "#####", "     ", "     "},
+	'/':  {"     ", "    #", "   # ", "  #  ", " #   ", "#    ", "     "},
+	'#':  {" # # ", " # # ", "#####", " # # ", "#####", " # # ", " # # "},
+	'%':  {"##   ", "##  #", "   # ", "  #  ", " #   ", "#  ##", "   ##"},
+	'(':  {"   # ", "  #  ", " #   ", " #   ", " #   ", "  #  ", "   # "},
+	')':  {" #   ", "  #  ", "   # ", "   # ", "   # ", "  #  ", " #   "},
+	'[':  {" ### ", " #   ", " #   ", " #   ", " #   ", " #   ", " ### "},
+	']':  {" ### ", "   # ", "   # ", "   # ", "   # ", "   # ", " ### "},
+	'!':  {"  #  ", "  #  ", "  #  ", "  #  ", "  #  ", "     ", "  #  "},
+	'?':  {" ### ", "#   #", "    #", "   # ", "  #  ", "     ", "  #  "},
+	'\'': {"  #  ", "  #  ", " #   ", "     ", "     ", "     ", "     "},
+	'"':  {" # # ", " # # ", "     ", "     ", "     ", "     ", "     "},
+	'_':  {"     ", "     ", "     ", "     ", "     ", "     ", "#####"},
+	'*':  {"     ", "  #  ", "# # #", " ### ", "# # #", "  #  ", "     "},
+	'<':  {"   # ", "  #  ", " #   ", "#    ", " #   ", "  #  ", "   # "},
+	'>':  {" #   ", "  #  ", "   # ", "    #", "   # ", "  #  ", " #   "},
+}
+
+// textSize returns the size in pixels of the text when drawn at the given scale.
+func textSize(text string, scale int) image.Point {
+	lines := strings.Split(text, "\n")
+	width := 0
+	for _, line := range lines {
+		if n := len([]rune(line)); n > width {
+			width = n
+		}
+	}
+	if width == 0 {
+		return image.Point{}
+	}
+	return image.Pt((width*glyphAdvance-1)*scale, (len(lines)*lineAdvance-2)*scale)
+}
+
+// drawText draws the text with its top left corner at pt, scaling every font pixel up to a square of scale pixels.
+// Pixels outside of dst are skipped.
+func drawText(dst draw.Image, pt image.Point, text string, c color.Color, scale int) {
+	bounds := dst.Bounds()
+	for lineIndex, line := range strings.Split(text, "\n") {
+		x := pt.X
+		y := pt.Y + lineIndex*lineAdvance*scale
+		for _, r := range line {
+			glyph, ok := glyphs[unicode.ToUpper(r)]
+			if !ok {
+				glyph = glyphs['?']
+			}
+			for row, bits := range glyph {
+				for col, bit := range bits {
+					if bit != '#' {
+						continue
+					}
+					square := image.Rect(x+col*scale, y+row*scale, x+(col+1)*scale, y+(row+1)*scale).Intersect(bounds)
+					draw.Draw(dst, square, image.NewUniform(c), image.Point{}, draw.Src)
+				}
+			}
+			x += glyphAdvance * scale
+		}
+	}
+}
@@ -12,10 +12,68 @@ type RenderConfig struct {
 	Zoom          float64
 	TargetX       float64
 	TargetY       float64
+	// Mapping controls how escape values are mapped onto the palette.
+	Mapping ColorMapping
 }
 
+// Render renders into a paletted image; points inside the set get the last palette entry.
+// A Mapping with an Interior color must be applied to the palette separately.
 func Render(cfg RenderConfig, image *image.Paletted, palette color.Palette) error {
-	s := newScaler(image.Bounds().Dx(), image.Bounds().Dy())
+	if len(palette) > 256 {
+		return fmt.Errorf("palette size (%d) exceeds 256 entries", len(palette))
+	}
+	return render(cfg, image.Bounds(), func(x, y int, severity float64) error {
+		idx := len(palette) - 1
+		if severity < 1 {
+			idx = int(float64(len(palette)-1) * cfg.Mapping.position(severity, cfg.MaxIterations, len(palette)-1))
+			if idx == len(palette)-1 && idx > 0 {
+				// Only points inside the set get the interior color.
+				idx--
+			}
+		}
+		//fmt.Printf("%3d,%3d %d\n", x, y, idx)
+		if idx < 0 || idx >= len(palette) {
+			return fmt.Errorf("palette index (%d) out of bounds", idx)
+		}
+		image.SetColorIndex(x, y, uint8(idx))
+		return nil
+	})
+}
+
+// RenderRGBA renders in true color.
+// Rather than picking the nearest palette entry, colors are blended between neighbouring entries of the gradient,
+// which is every entry but the last; that one holds the interior color, unless the Mapping sets one.
+func RenderRGBA(cfg RenderConfig, image *image.RGBA, palette color.Palette) error {
+	if len(palette) == 0 {
+		return fmt.Errorf("empty palette")
+	}
+	interior := cfg.Mapping.interior(palette)
+	gradient := palette[:len(palette)-1]
+	if len(gradient) == 0 {
+		gradient = palette
+	}
+	last := len(gradient) - 1
+	return render(cfg, image.Bounds(), func(x, y int, severity float64) error {
+		if severity >= 1 {
+			image.Set(x, y, interior)
+			return nil
+		}
+		pos := float64(last) * cfg.Mapping.position(severity, cfg.MaxIterations, len(gradient))
+		idx := int(pos)
+		if idx < 0 || idx > last {
+			return fmt.Errorf("palette index (%d) out of bounds", idx)
+		}
+		if idx == last {
+			image.Set(x, y, gradient[idx])
+			return nil
+		}
+		image.SetRGBA(x, y, blendRGBA(gradient[idx], gradient[idx+1], pos-float64(idx)))
+		return nil
+	})
+}
+
+func render(cfg RenderConfig, bounds image.Rectangle, set func(x, y int, severity float64) error) error {
+	s := newScaler(bounds.Dx(), bounds.Dy())
 	if err := s.Zoom(cfg.Zoom); err != nil {
 		return fmt.Errorf("setting zoom (%f): %w", cfg.Zoom, err)
 	}
@@ -23,8 +81,8 @@ func Render(cfg RenderConfig, image *image.Paletted, palette color.Palette) erro
 		return fmt.Errorf("targeting: %w", err)
 	}
 	pg := newPixelGenerator(cfg.MaxIterations)
-	for x := 0; x < image.Bounds().Dx(); x++ {
-		for y := 0; y < image.Bounds().Dy(); y++ {
+	for x := 0; x < bounds.Dx(); x++ {
+		for y := 0; y < bounds.Dy(); y++ {
 			mx, my, err := s.Transform(x, y)
 			if err != nil {
 				return fmt.Errorf("scaling pixel: %w", err)
@@ -34,18 +92,28 @@ func Render(cfg RenderConfig, image *image.Paletted, palette color.Palette) erro
 			if severity < 0 || severity > 1.0 {
 				return fmt.Errorf("severity (%f) out of bounds", severity)
 			}
-			idx := int(float64(len(palette)-1) * severity)
-			//fmt.Printf("%3d,%3d %d\n", x, y, idx)
-			if idx < 0 || idx >= len(palette) {
-				return fmt.Errorf("palette index (%d) out of bounds", idx)
+			if err := set(bounds.Min.X+x, bounds.Min.Y+y, severity); err != nil {
+				return err
 			}
-			c := palette[idx]
-			image.Set(x, y, c)
 		}
 	}
 	return nil
 }
 
+func blendRGBA(from, to color.Color, fraction float64) color.RGBA {
+	fr, fg, fb, fa := from.RGBA()
+	tr, tg, tb, ta := to.RGBA()
+	blend := func(f, t uint32) uint8 {
+		return uint8((float64(f) + (float64(t)-float64(f))*fraction) / 257)
+	}
+	return color.RGBA{
+		R: blend(fr, tr),
+		G: blend(fg, tg),
+		B: blend(fb, tb),
+		A: blend(fa, ta),
+	}
+}
+
 type pixelGenerator struct {
 	maxIterations int
 }
@@ -0,0 +1,36 @@
+package mandelbrot
+
+import (
+	"github.com/stretchr/testify/require"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestRenderRGBAInterior(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	green := color.RGBA{G: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	palette := color.Palette{red, green, blue}
+	// The density saturates the escape value of most pixels, which puts them at the end of the gradient.
+	rc := RenderConfig{MaxIterations: 50, Zoom: 1, TargetX: 0.5, TargetY: 0.5, Mapping: ColorMapping{Density: 4}}
+	img := image.NewRGBA(image.Rect(0, 0, 32, 24))
+	require.NoError(t, RenderRGBA(rc, img, palette))
+	var inside, end int
+	for y := 0; y < 24; y++ {
+		for x := 0; x < 32; x++ {
+			c := img.RGBAAt(x, y)
+			switch {
+			case c == blue:
+				inside++
+			case c == green:
+				end++
+			default:
+				// Escaped pixels are blended between the gradient entries, never towards the interior color.
+				require.Zero(t, c.B, "pixel (%d,%d)", x, y)
+			}
+		}
+	}
+	require.Greater(t, inside, 0)
+	require.Greater(t, end, 0)
+}
@@ -0,0 +1,139 @@
+package mandelbrot
+
+import (
+	"fmt"
+	"image"
+	"time"
+)
+
+// gifOptimizer keeps track of what is shown on screen, so that only changes need to be written.
+type gifOptimizer struct {
+	// pending is the last received frame, which is held back in case the next frame is identical.
+	pending      *image.Paletted
+	pendingRGB   []uint32
+	pendingDelay time.Duration
+	// canvas contains the color of every pixel after the last written frame,
+	// or nil if nothing has been written yet.
+	canvas []uint32
+}
+
+func (s *GIFSink) writeOptimized(pm *image.Paletted, delay time.Duration) error {
+	if pm.Bounds() != image.Rect(0, 0, s.width, s.height) {
+		return fmt.Errorf("frame bounds (%v) do not cover the whole image", pm.Bounds())
+	}
+	o := &s.optimizer
+	rgb := displayedColors(pm)
+	if o.pending != nil && equalColors(rgb, o.pendingRGB) && o.pendingDelay+delay <= gifMaxDelay {
+		o.pendingDelay += delay
+		return nil
+	}
+	if err := s.flushOptimized(); err != nil {
+		return err
+	}
+	o.pending = pm
+	o.pendingRGB = rgb
+	o.pendingDelay = delay
+	return nil
+}
+
+// flushOptimized writes the pending frame, cropped to the area that differs from the canvas.
+// Pixels within that area that did not change are made transparent, if the palette has an index to spare
+// and doing so makes the frame smaller; scattered transparent pixels can compress worse than the original.
+func (s *GIFSink) flushOptimized() error {
+	o := &s.optimizer
+	if o.pending == nil {
+		return nil
+	}
+	pm, rgb, delay := o.pending, o.pendingRGB, s.delays.Next(o.pendingDelay)
+	o.pending, o.pendingRGB, o.pendingDelay = nil, nil, 0
+	if o.canvas == nil {
+		o.canvas = rgb
+		return s.writeImage(pm, delay, gifDisposalNone, -1)
+	}
+	changed := image.Rectangle{}
+	for y := 0; y < s.height; y++ {
+		for x := 0; x < s.width; x++ {
+			if rgb[y*s.width+x] != o.canvas[y*s.width+x] {
+				changed = changed.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	if changed.Empty() {
+		changed = image.Rect(0, 0, 1, 1)
+	}
+	var used [256]bool
+	for y := changed.Min.Y; y < changed.Max.Y; y++ {
+		for x := changed.Min.X; x < changed.Max.X; x++ {
+			if rgb[y*s.width+x] != o.canvas[y*s.width+x] {
+				used[pm.ColorIndexAt(x, y)] = true
+			}
+		}
+	}
+	_, sizeBits, err := gifColorTable(pm.Palette)
+	if err != nil {
+		return fmt.Errorf("encoding color table: %w", err)
+	}
+	transparentIndex := -1
+	for i := 0; i < 1<<(sizeBits+1); i++ {
+		if !used[i] {
+			transparentIndex = i
+			break
+		}
+	}
+	cropped := pm.SubImage(changed).(*image.Paletted)
+	encoded, err := s.encodeImage(cropped, delay, gifDisposalNone, -1)
+	if err != nil {
+		return err
+	}
+	if transparentIndex >= 0 {
+		masked := image.NewPaletted(changed, pm.Palette)
+		for y := changed.Min.Y; y < changed.Max.Y; y++ {
+			for x := changed.Min.X; x < changed.Max.X; x++ {
+				idx := pm.ColorIndexAt(x, y)
+				if rgb[y*s.width+x] == o.canvas[y*s.width+x] {
+					idx = uint8(transparentIndex)
+				}
+				masked.SetColorIndex(x, y, idx)
+			}
+		}
+		encodedMasked, err := s.encodeImage(masked, delay, gifDisposalNone, transparentIndex)
+		if err != nil {
+			return err
+		}
+		if len(encodedMasked) < len(encoded) {
+			encoded = encodedMasked
+		}
+	}
+	o.canvas = rgb
+	_, err = s.w.Write(encoded)
+	return err
+}
+
+// displayedColors returns the RGB value of every pixel, as it is stored in a GIF color table.
+func displayedColors(pm *image.Paletted) []uint32 {
+	table := make([]uint32, len(pm.Palette))
+	for i, c := range pm.Palette {
+		r, g, b, _ := c.RGBA()
+		table[i] = (r>>8)<<16 | (g>>8)<<8 | b>>8
+	}
+	b := pm.Bounds()
+	rgb := make([]uint32, 0, b.Dx()*b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			rgb = append(rgb, table[pm.ColorIndexAt(x, y)])
+		}
+	}
+	return rgb
+}
+
+func equalColors(l, r []uint32) bool {
+	if len(l) != len(r) {
+		return false
+	}
+	for i := range l {
+		if l[i] != r[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,261 @@
+package mandelbrot
+
+import (
+	"bufio"
+	"bytes"
+	"compress/lzw"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"time"
+)
+
+const (
+	gifExtensionIntroducer = 0x21
+	gifImageSeparator      = 0x2c
+	gifTrailer             = 0x3b
+	gifColorTableFlag      = 0x80
+)
+
+const (
+	gifDisposalUnspecified = 0
+	// gifDisposalNone leaves the frame in place, so that the next frame is drawn on top of it.
+	gifDisposalNone = 1
+)
+
+// gifMaxDelay is the longest delay a single GIF frame can have.
+const gifMaxDelay = 0xffff * 10 * time.Millisecond
+
+// GIFSink writes frames to an animated GIF as they come in,
+// so that frames do not need to be kept in memory.
+// Paletted frames are written as they are; other frames are quantized to the palette,
+// which TrueColor asks for when Dither or PaletteMode need it.
+type GIFSink struct {
+	w       *bufio.Writer
+	width   int
+	height  int
+	palette color.Palette
+	// LoopCount is the number of times the animation is repeated; 0 loops forever and -1 shows it once.
+	// It must be set before the first frame is written.
+	LoopCount int
+	// Optimize only stores the part of every frame that changed, and merges identical consecutive frames.
+	// It must be set before the first frame is written.
+	Optimize bool
+	// Dither is the dithering used when quantizing true color frames; one of the Dither constants.
+	// It must be set before the first frame is written.
+	Dither string
+	// PaletteMode chooses between quantizing to the palette of the sink, or to a palette per frame;
+	// one of the GIFPalette constants. It must be set before the first frame is written.
+	PaletteMode   string
+	quantizer     *gifQuantizer
+	globalTable   []byte
+	headerWritten bool
+	optimizer     gifOptimizer
+	delays        delayScheduler
+}
+
+func NewGIFSink(w io.Writer, width, height int, palette color.Palette) *GIFSink {
+	return &GIFSink{
+		w:       bufio.NewWriter(w),
+		width:   width,
+		height:  height,
+		palette: palette,
+	}
+}
+
+// TrueColor asks for true color frames when they are dithered, or quantized to an adaptive palette.
+func (s *GIFSink) TrueColor() bool {
+	return (s.Dither != "" && s.Dither != DitherNone) || s.PaletteMode == GIFPaletteAdaptive
+}
+
+func (s *GIFSink) WriteFrame(frame Frame) error {
+	pm, ok := frame.Image.(*image.Paletted)
+	if !ok {
+		if s.quantizer == nil {
+			quantizer, err := newGIFQuantizer(s.Dither, s.PaletteMode, s.palette)
+			if err != nil {
+				return fmt.Errorf("creating quantizer: %w", err)
+			}
+			s.quantizer = quantizer
+		}
+		pm = s.quantizer.Quantize(frame.Image)
+	}
+	if err := s.writeHeader(); err != nil {
+		return fmt.Errorf("writing header: %w", err)
+	}
+	if s.Optimize {
+		if err := s.writeOptimized(pm, frame.Delay); err != nil {
+			return fmt.Errorf("writing frame (%d): %w", frame.Index, err)
+		}
+		return nil
+	}
+	if err := s.writeImage(pm, s.delays.Next(frame.Delay), gifDisposalUnspecified, -1); err != nil {
+		return fmt.Errorf("writing frame (%d): %w", frame.Index, err)
+	}
+	return nil
+}
+
+func (s *GIFSink) Close() error {
+	if err := s.writeHeader(); err != nil {
+		return fmt.Errorf("writing header: %w", err)
+	}
+	if err := s.flushOptimized(); err != nil {
+		return fmt.Errorf("writing last frame: %w", err)
+	}
+	if err := s.w.WriteByte(gifTrailer); err != nil {
+		return fmt.Errorf("writing trailer: %w", err)
+	}
+	return s.w.Flush()
+}
+
+func (s *GIFSink) writeHeader() error {
+	if s.headerWritten {
+		return nil
+	}
+	s.headerWritten = true
+	if s.width <= 0 || s.width >= 1<<16 || s.height <= 0 || s.height >= 1<<16 {
+		return fmt.Errorf("invalid dimensions (%d,%d)", s.width, s.height)
+	}
+	table, sizeBits, err := gifColorTable(s.palette)
+	if err != nil {
+		return fmt.Errorf("encoding global color table: %w", err)
+	}
+	s.globalTable = table
+	var buf bytes.Buffer
+	buf.WriteString("GIF89a")
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(s.width))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(s.height))
+	buf.Write([]byte{gifColorTableFlag | sizeBits, 0x00, 0x00})
+	buf.Write(table)
+	if s.LoopCount >= 0 {
+		buf.Write([]byte{gifExtensionIntroducer, 0xff, 0x0b})
+		buf.WriteString("NETSCAPE2.0")
+		buf.Write([]byte{0x03, 0x01})
+		_ = binary.Write(&buf, binary.LittleEndian, uint16(s.LoopCount))
+		buf.WriteByte(0x00)
+	}
+	_, err = s.w.Write(buf.Bytes())
+	return err
+}
+
+// writeImage writes a graphic control extension and image block.
+// The position of the image block is taken from the bounds of pm.
+// A transparentIndex of -1 means no color is transparent.
+func (s *GIFSink) writeImage(pm *image.Paletted, delay int, disposal byte, transparentIndex int) error {
+	encoded, err := s.encodeImage(pm, delay, disposal, transparentIndex)
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(encoded)
+	return err
+}
+
+func (s *GIFSink) encodeImage(pm *image.Paletted, delay int, disposal byte, transparentIndex int) ([]byte, error) {
+	b := pm.Bounds()
+	if !b.In(image.Rect(0, 0, s.width, s.height)) {
+		return nil, fmt.Errorf("image bounds (%v) out of bounds", b)
+	}
+	table, sizeBits, err := gifColorTable(pm.Palette)
+	if err != nil {
+		return nil, fmt.Errorf("encoding color table: %w", err)
+	}
+	var buf bytes.Buffer
+	var flags byte
+	if transparentIndex >= 0 {
+		flags = 0x01
+	}
+	buf.Write([]byte{gifExtensionIntroducer, 0xf9, 0x04, disposal<<2 | flags})
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(delay))
+	buf.Write([]byte{byte(transparentIndex), 0x00})
+
+	buf.WriteByte(gifImageSeparator)
+	for _, v := range []int{b.Min.X, b.Min.Y, b.Dx(), b.Dy()} {
+		_ = binary.Write(&buf, binary.LittleEndian, uint16(v))
+	}
+	if bytes.Equal(table, s.globalTable) {
+		buf.WriteByte(0x00)
+	} else {
+		buf.WriteByte(gifColorTableFlag | sizeBits)
+		buf.Write(table)
+	}
+
+	litWidth := int(sizeBits) + 1
+	if litWidth < 2 {
+		litWidth = 2
+	}
+	buf.WriteByte(byte(litWidth))
+	var compressed bytes.Buffer
+	lw := lzw.NewWriter(&compressed, lzw.LSB, litWidth)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		offset := pm.PixOffset(b.Min.X, y)
+		if _, err := lw.Write(pm.Pix[offset : offset+b.Dx()]); err != nil {
+			return nil, fmt.Errorf("compressing: %w", err)
+		}
+	}
+	if err := lw.Close(); err != nil {
+		return nil, fmt.Errorf("compressing: %w", err)
+	}
+	data := compressed.Bytes()
+	for len(data) > 0 {
+		n := len(data)
+		if n > 255 {
+			n = 255
+		}
+		buf.WriteByte(byte(n))
+		buf.Write(data[:n])
+		data = data[n:]
+	}
+	buf.WriteByte(0x00)
+	return buf.Bytes(), nil
+}
+
+// gifColorTable encodes a palette, padded to a power of two entries.
+// The size bits encode a table size of 2^(sizeBits+1).
+func gifColorTable(palette color.Palette) ([]byte, byte, error) {
+	if len(palette) == 0 || len(palette) > 256 {
+		return nil, 0, fmt.Errorf("invalid palette size (%d)", len(palette))
+	}
+	var sizeBits byte
+	for 1<<(sizeBits+1) < len(palette) {
+		sizeBits++
+	}
+	table := make([]byte, 3*(1<<(sizeBits+1)))
+	for i, c := range palette {
+		if c == nil {
+			return nil, 0, fmt.Errorf("nil color at index (%d)", i)
+		}
+		r, g, b, _ := c.RGBA()
+		table[3*i] = byte(r >> 8)
+		table[3*i+1] = byte(g >> 8)
+		table[3*i+2] = byte(b >> 8)
+	}
+	return table, sizeBits, nil
+}
+
+// gifMinDelay is the shortest delay, in hundredths of a second, that browsers show as is;
+// shorter delays are played at 100ms.
+const gifMinDelay = 2
+
+// delayScheduler converts frame delays to the hundredths of a second GIF uses.
+// Instead of rounding every delay on its own, it rounds the time since the start of the animation,
+// so that the rounding errors are spread over the frames rather than adding up.
+// Delays are at least gifMinDelay; the following frames are shortened to catch up where they can,
+// so above 50 FPS the animation plays slower than configured.
+type delayScheduler struct {
+	elapsed time.Duration
+	ticks   int
+}
+
+func (ds *delayScheduler) Next(delay time.Duration) int {
+	const tick = 10 * time.Millisecond
+	ds.elapsed += delay
+	total := int((ds.elapsed + tick/2) / tick)
+	ticks := total - ds.ticks
+	if ticks < gifMinDelay {
+		ticks = gifMinDelay
+	}
+	ds.ticks += ticks
+	return ticks
+}
@@ -0,0 +1,118 @@
+package mandelbrot
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"testing"
+	"time"
+)
+
+func TestGIFSink(t *testing.T) {
+	palette := Gradient(color.RGBA{B: 255, A: 255}, color.RGBA{A: 255}, 5)
+	var frames []*image.Paletted
+	for i := 0; i < 3; i++ {
+		img := image.NewPaletted(image.Rect(0, 0, 7, 4), CyclePalette(palette, float64(i)))
+		for j := range img.Pix {
+			img.Pix[j] = uint8((i + j) % len(palette))
+		}
+		frames = append(frames, img)
+	}
+	var buf bytes.Buffer
+	sink := NewGIFSink(&buf, 7, 4, palette)
+	for i, img := range frames {
+		require.NoError(t, sink.WriteFrame(Frame{Index: i, Delay: 50 * time.Millisecond, Image: img}))
+	}
+	require.NoError(t, sink.Close())
+
+	g, err := gif.DecodeAll(&buf)
+	require.NoError(t, err)
+	require.Equal(t, 0, g.LoopCount)
+	require.Equal(t, []int{5, 5, 5}, g.Delay)
+	require.Len(t, g.Image, len(frames))
+	for i, img := range frames {
+		for y := 0; y < 4; y++ {
+			for x := 0; x < 7; x++ {
+				require.Equal(t, rgba(img.At(x, y)), rgba(g.Image[i].At(x, y)), "frame %d pixel (%d,%d)", i, x, y)
+			}
+		}
+	}
+}
+
+func rgba(c color.Color) color.RGBA {
+	return color.RGBAModel.Convert(c).(color.RGBA)
+}
+
+func TestGIFSinkOptimizeLossless(t *testing.T) {
+	const width, height = 12, 9
+	palette := Gradient(color.RGBA{R: 255, A: 255}, color.RGBA{B: 255, A: 255}, 256)
+	var frames []*image.Paletted
+	base := image.NewPaletted(image.Rect(0, 0, width, height), palette)
+	for i := range base.Pix {
+		base.Pix[i] = uint8(i * 7)
+	}
+	for i := 0; i < 8; i++ {
+		img := image.NewPaletted(base.Rect, palette)
+		copy(img.Pix, base.Pix)
+		switch {
+		case i == 2 || i == 3:
+			// Identical to the previous frame.
+			img.Pix[5] = 200
+		case i == 4:
+			img.Pix[5] = 200
+			img.SetColorIndex(3, 4, 17)
+			img.SetColorIndex(8, 6, 250)
+		case i == 5:
+			// Only the palette changes.
+			img.Palette = CyclePalette(palette, 3)
+		case i >= 6:
+			img.Pix[width*height-1] = uint8(i)
+		}
+		if i == 1 {
+			img.Pix[5] = 200
+		}
+		frames = append(frames, img)
+	}
+	encode := func(optimize bool) []byte {
+		var buf bytes.Buffer
+		sink := NewGIFSink(&buf, width, height, palette)
+		sink.Optimize = optimize
+		for i, img := range frames {
+			require.NoError(t, sink.WriteFrame(Frame{Index: i, Delay: 100 * time.Millisecond, Image: img}))
+		}
+		require.NoError(t, sink.Close())
+		return buf.Bytes()
+	}
+	naive := encode(false)
+	optimized := encode(true)
+	require.Less(t, len(optimized), len(naive))
+	require.Equal(t, displayedTimeline(t, naive), displayedTimeline(t, optimized))
+}
+
+type displayedFrame struct {
+	pix   []uint8
+	delay int
+}
+
+// displayedTimeline composes the frames of a GIF as a viewer would, merging identical consecutive frames.
+func displayedTimeline(t *testing.T, b []byte) []displayedFrame {
+	g, err := gif.DecodeAll(bytes.NewReader(b))
+	require.NoError(t, err)
+	canvas := image.NewRGBA(image.Rect(0, 0, g.Config.Width, g.Config.Height))
+	var timeline []displayedFrame
+	for i, img := range g.Image {
+		require.NotEqual(t, gif.DisposalBackground, g.Disposal[i])
+		require.NotEqual(t, gif.DisposalPrevious, g.Disposal[i])
+		draw.Draw(canvas, img.Bounds(), img, img.Bounds().Min, draw.Over)
+		pix := append([]uint8{}, canvas.Pix...)
+		if len(timeline) > 0 && bytes.Equal(timeline[len(timeline)-1].pix, pix) {
+			timeline[len(timeline)-1].delay += g.Delay[i]
+			continue
+		}
+		timeline = append(timeline, displayedFrame{pix: pix, delay: g.Delay[i]})
+	}
+	return timeline
+}
@@ -1,6 +1,7 @@
 package mandelbrot
 
 import (
+	"fmt"
 	"image/color"
 )
 
@@ -48,3 +49,81 @@ func (in *Interpolator) At(index int) float64 {
 	change := diff * fraction
 	return in.from + change
 }
+
+// GradientStop is a color at a position between 0 and 1 in a StopGradient.
+type GradientStop struct {
+	Position float64
+	Color    color.Color
+}
+
+// StopGradient is a gradient through any number of color stops.
+// Before the first and after the last stop, the color of that stop is used.
+type StopGradient struct {
+	// Stops must be in order of position; stops may share a position, to create a sharp transition.
+	Stops []GradientStop
+	// ColorSpace selects the space in which colors are interpolated; it defaults to OKLab.
+	ColorSpace string
+}
+
+func (g StopGradient) Validate() error {
+	if len(g.Stops) == 0 {
+		return fmt.Errorf("at least one stop is required")
+	}
+	if g.ColorSpace != "" {
+		if err := validateColorSpace(g.ColorSpace); err != nil {
+			return err
+		}
+	}
+	for i, stop := range g.Stops {
+		if stop.Color == nil {
+			return fmt.Errorf("stop (%d) has no color", i)
+		}
+		if stop.Position < 0 || stop.Position > 1 {
+			return fmt.Errorf("stop (%d) position (%f) is outside of 0-1", i, stop.Position)
+		}
+		if i > 0 && stop.Position < g.Stops[i-1].Position {
+			return fmt.Errorf("stop (%d) position (%f) is before the previous stop (%f)", i, stop.Position, g.Stops[i-1].Position)
+		}
+	}
+	return nil
+}
+
+// At returns the color at the given position.
+func (g StopGradient) At(position float64) color.RGBA {
+	space := g.ColorSpace
+	if space == "" {
+		space = ColorSpaceOKLab
+	}
+	stops := g.Stops
+	if position <= stops[0].Position {
+		return color.RGBAModel.Convert(stops[0].Color).(color.RGBA)
+	}
+	for i := 1; i < len(stops); i++ {
+		from, to := stops[i-1], stops[i]
+		if position > to.Position {
+			continue
+		}
+		u := (position - from.Position) / (to.Position - from.Position)
+		return mixColors(space, from.Color, to.Color, u)
+	}
+	return color.RGBAModel.Convert(stops[len(stops)-1].Color).(color.RGBA)
+}
+
+// Palette samples the gradient at size evenly spaced positions, including both ends.
+func (g StopGradient) Palette(size int) (color.Palette, error) {
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid palette size (%d)", size)
+	}
+	p := make(color.Palette, size)
+	for i := range p {
+		position := 1.0
+		if size > 1 {
+			position = float64(i) / float64(size-1)
+		}
+		p[i] = g.At(position)
+	}
+	return p, nil
+}
@@ -67,3 +67,112 @@ func TestGradient(t *testing.T) {
 		})
 	}
 }
+
+func TestStopGradient(t *testing.T) {
+	black := color.RGBA{A: 255}
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	var tests = []struct {
+		desc   string
+		space  string
+		from   color.RGBA
+		to     color.RGBA
+		result color.RGBA
+	}{
+		{
+			desc:   "srgb midpoint",
+			space:  ColorSpaceSRGB,
+			from:   black,
+			to:     white,
+			result: color.RGBA{R: 128, G: 128, B: 128, A: 255},
+		},
+		{
+			desc:   "gamma correct midpoint",
+			space:  ColorSpaceLinearRGB,
+			from:   black,
+			to:     white,
+			result: color.RGBA{R: 188, G: 188, B: 188, A: 255},
+		},
+		{
+			desc:   "hsl takes the short way around",
+			space:  ColorSpaceHSL,
+			from:   red,
+			to:     blue,
+			result: color.RGBA{R: 255, B: 255, A: 255},
+		},
+		{
+			desc:   "hsl keeps the hue towards gray",
+			space:  ColorSpaceHSL,
+			from:   red,
+			to:     white,
+			result: color.RGBA{R: 223, G: 159, B: 159, A: 255},
+		},
+		{
+			desc:   "cielab midpoint lightness",
+			space:  ColorSpaceCIELAB,
+			from:   black,
+			to:     white,
+			result: color.RGBA{R: 119, G: 119, B: 119, A: 255},
+		},
+		{
+			desc:   "oklab midpoint lightness",
+			space:  ColorSpaceOKLab,
+			from:   black,
+			to:     white,
+			result: color.RGBA{R: 99, G: 99, B: 99, A: 255},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			g := StopGradient{
+				Stops:      []GradientStop{{Position: 0, Color: test.from}, {Position: 1, Color: test.to}},
+				ColorSpace: test.space,
+			}
+			require.NoError(t, g.Validate())
+			require.Equal(t, test.from, g.At(0))
+			require.Equal(t, test.to, g.At(1))
+			require.Equal(t, test.result, g.At(0.5))
+		})
+	}
+}
+
+func TestStopGradientStops(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	green := color.RGBA{G: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	g := StopGradient{
+		Stops: []GradientStop{
+			{Position: 0.2, Color: red},
+			{Position: 0.5, Color: green},
+			{Position: 0.5, Color: blue},
+		},
+	}
+	palette, err := g.Palette(11)
+	require.NoError(t, err)
+	require.Equal(t, red, palette[0], "before the first stop")
+	require.Equal(t, red, palette[2])
+	require.Equal(t, green, palette[5], "sharp transition")
+	require.Equal(t, blue, palette[6])
+	require.Equal(t, blue, palette[10], "after the last stop")
+
+	g.Stops[1].Position = 0.1
+	require.EqualError(t, g.Validate(), "stop (1) position (0.100000) is before the previous stop (0.200000)")
+	_, err = StopGradient{Stops: []GradientStop{{Position: 0, Color: red}}, ColorSpace: "cmyk"}.Palette(2)
+	require.Error(t, err)
+}
+
+func TestColorSpaceRoundTrip(t *testing.T) {
+	for _, space := range []string{ColorSpaceSRGB, ColorSpaceLinearRGB, ColorSpaceHSL, ColorSpaceHCL, ColorSpaceCIELAB, ColorSpaceOKLab} {
+		for _, c := range []color.RGBA{{A: 255}, {R: 255, G: 255, B: 255, A: 255}, {R: 12, G: 200, B: 99, A: 255}, {R: 250, G: 3, B: 140, A: 255}} {
+			v, alpha := toColorSpace(space, c)
+			require.Equal(t, c, fromColorSpace(space, v, alpha), "%s %v", space, c)
+		}
+	}
+	white, _ := toColorSpace(ColorSpaceOKLab, color.White)
+	require.InDelta(t, 1, white[0], 1e-6)
+	require.InDelta(t, 0, white[1], 1e-6)
+	require.InDelta(t, 0, white[2], 1e-6)
+	white, _ = toColorSpace(ColorSpaceCIELAB, color.White)
+	require.InDelta(t, 100, white[0], 1e-3)
+}
@@ -0,0 +1,89 @@
+package mandelbrot
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// IterationModeFixed uses MaxIterations as configured, interpolated between keyframes.
+	IterationModeFixed = "fixed"
+	// IterationModeAuto scales MaxIterations with the zoom level.
+	IterationModeAuto = "auto"
+	// IterationModeAdaptive starts from the auto value and keeps raising MaxIterations
+	// until the mandelbrot boundary stops changing.
+	IterationModeAdaptive = "adaptive"
+)
+
+const (
+	adaptiveSampleWidth  = 64
+	adaptiveSampleHeight = 48
+	// adaptiveThreshold is the change in max-iteration fraction below which the iteration count is considered stable.
+	adaptiveThreshold = 0.002
+)
+
+// AutoMaxIterations derives the iteration count from the zoom level.
+// Deeper zooms resolve finer detail and so need more iterations;
+// base is the iteration count at a zoom of 1.
+func AutoMaxIterations(base int, zoom float64) int {
+	if zoom < 1 {
+		zoom = 1
+	}
+	return int(math.Round(float64(base) * (1 + math.Log10(zoom))))
+}
+
+// AdaptiveMaxIterations doubles the MaxIterations of rc, up to limit, until the fraction of
+// max-iteration pixels stabilises; see maxIterationFraction.
+func AdaptiveMaxIterations(rc RenderConfig, limit int) (int, error) {
+	if rc.MaxIterations <= 0 {
+		return 0, fmt.Errorf("invalid MaxIterations (%d)", rc.MaxIterations)
+	}
+	s := newScaler(adaptiveSampleWidth, adaptiveSampleHeight)
+	if err := s.Zoom(rc.Zoom); err != nil {
+		return 0, fmt.Errorf("setting zoom (%f): %w", rc.Zoom, err)
+	}
+	if err := s.Target(rc.TargetX, rc.TargetY); err != nil {
+		return 0, fmt.Errorf("targeting: %w", err)
+	}
+	iterations := rc.MaxIterations
+	prev, err := maxIterationFraction(s, iterations)
+	if err != nil {
+		return 0, fmt.Errorf("sampling (%d iterations): %w", iterations, err)
+	}
+	for iterations < limit {
+		next := iterations * 2
+		if next > limit {
+			next = limit
+		}
+		fraction, err := maxIterationFraction(s, next)
+		if err != nil {
+			return 0, fmt.Errorf("sampling (%d iterations): %w", next, err)
+		}
+		if math.Abs(fraction-prev) < adaptiveThreshold {
+			return iterations, nil
+		}
+		iterations = next
+		prev = fraction
+	}
+	return iterations, nil
+}
+
+// maxIterationFraction returns the share of the sampled pixels that reach maxIterations.
+// Raising the iterations only changes pixels at the boundary of the set, where points just outside it
+// no longer pass for inside, so the share stabilises once the boundary is resolved.
+func maxIterationFraction(s *scaler, maxIterations int) (float64, error) {
+	pg := newPixelGenerator(maxIterations)
+	maxed := 0
+	for x := 0; x < adaptiveSampleWidth; x++ {
+		for y := 0; y < adaptiveSampleHeight; y++ {
+			mx, my, err := s.Transform(x, y)
+			if err != nil {
+				return 0, fmt.Errorf("scaling pixel: %w", err)
+			}
+			if pg.Render(mx, my) >= 1 {
+				maxed++
+			}
+		}
+	}
+	return float64(maxed) / float64(adaptiveSampleWidth*adaptiveSampleHeight), nil
+}
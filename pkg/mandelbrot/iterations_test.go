@@ -0,0 +1,78 @@
+package mandelbrot
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestAutoMaxIterations(t *testing.T) {
+	require.Equal(t, 100, AutoMaxIterations(100, 1))
+	// Zooms below 1 are treated as 1.
+	require.Equal(t, 100, AutoMaxIterations(100, 0.5))
+	prev := AutoMaxIterations(100, 1)
+	for _, zoom := range []float64{2, 10, 100, 1e4, 1e8, 1e12} {
+		iterations := AutoMaxIterations(100, zoom)
+		require.Greater(t, iterations, prev, "zoom %g", zoom)
+		prev = iterations
+	}
+}
+
+func TestRenderConfigIterationLimit(t *testing.T) {
+	var tests = []struct {
+		desc  string
+		mode  string
+		limit int
+		zoom  float64
+		want  int
+	}{
+		{desc: "auto below the limit", mode: IterationModeAuto, limit: 1000, zoom: 100, want: 300},
+		{desc: "auto capped", mode: IterationModeAuto, limit: 250, zoom: 100, want: 250},
+		{desc: "auto default limit", mode: IterationModeAuto, zoom: 1e20, want: 1600},
+		{desc: "adaptive starts from auto", mode: IterationModeAdaptive, limit: 80, zoom: 100, want: 80},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			cfg := AnimationConfig{
+				MaxIterations:      100,
+				MaxIterationsLimit: test.limit,
+				IterationMode:      test.mode,
+			}
+			rc, err := cfg.renderConfig(CameraState{Zoom: test.zoom, TargetX: 0.5, TargetY: 0.5, MaxIterations: 100})
+			require.NoError(t, err)
+			require.Equal(t, test.want, rc.MaxIterations)
+		})
+	}
+}
+
+func TestAdaptiveMaxIterations(t *testing.T) {
+	// The whole set is in view, and at 4 iterations its boundary is a rough blob.
+	rc := RenderConfig{MaxIterations: 4, Zoom: 1, TargetX: 0.5, TargetY: 0.5}
+	iterations, err := AdaptiveMaxIterations(rc, 1024)
+	require.NoError(t, err)
+	require.Greater(t, iterations, 4)
+	require.LessOrEqual(t, iterations, 1024)
+
+	capped, err := AdaptiveMaxIterations(rc, 8)
+	require.NoError(t, err)
+	require.Equal(t, 8, capped)
+
+	// Far outside the set there is no boundary, so there is nothing to resolve.
+	rc = RenderConfig{MaxIterations: 4, Zoom: 1e3, TargetX: 0, TargetY: 0}
+	iterations, err = AdaptiveMaxIterations(rc, 1024)
+	require.NoError(t, err)
+	require.Equal(t, 4, iterations)
+
+	_, err = AdaptiveMaxIterations(RenderConfig{Zoom: 1}, 1024)
+	require.Error(t, err)
+}
+
+func TestMaxIterationFraction(t *testing.T) {
+	s := newScaler(adaptiveSampleWidth, adaptiveSampleHeight)
+	// With more iterations, fewer points near the boundary pass for inside.
+	coarse, err := maxIterationFraction(s, 4)
+	require.NoError(t, err)
+	fine, err := maxIterationFraction(s, 256)
+	require.NoError(t, err)
+	require.Greater(t, coarse, 0.0)
+	require.Less(t, fine, coarse)
+}
@@ -0,0 +1,104 @@
+package mandelbrot
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"sort"
+)
+
+const (
+	swatchMargin = 4
+	swatchHeight = 16
+)
+
+// paletteLibrary contains well-known fractal palettes, by name.
+var paletteLibrary = map[string]PaletteConfig{
+	// blue resembles the palette used when no palette is configured.
+	"blue": {
+		Stops:      []PaletteStop{{0, "#0000ff"}, {1, "#000000"}},
+		ColorSpace: ColorSpaceSRGB,
+	},
+	// ultra-fractal is the default gradient of Ultra Fractal, which wraps around.
+	"ultra-fractal": {
+		Stops: []PaletteStop{
+			{0, "#000764"}, {0.16, "#206bcb"}, {0.42, "#edffff"}, {0.6425, "#ffaa00"}, {0.8575, "#000200"}, {1, "#000764"},
+		},
+		ColorSpace: ColorSpaceSRGB,
+	},
+	"fire": {
+		Stops: []PaletteStop{
+			{0, "#000000"}, {0.25, "#7a0a00"}, {0.5, "#e23a00"}, {0.75, "#ffb300"}, {1, "#fff8d0"},
+		},
+		ColorSpace: ColorSpaceOKLab,
+	},
+	"ice": {
+		Stops: []PaletteStop{
+			{0, "#000010"}, {0.3, "#0a2a6e"}, {0.6, "#3c8cd2"}, {0.85, "#b4e6ff"}, {1, "#ffffff"},
+		},
+		ColorSpace: ColorSpaceOKLab,
+	},
+	"grayscale": {
+		Stops:      []PaletteStop{{0, "#000000"}, {1, "#ffffff"}},
+		ColorSpace: ColorSpaceSRGB,
+	},
+	"rainbow": {
+		Stops: []PaletteStop{
+			{0, "#ff0000"}, {0.2, "#ffff00"}, {0.4, "#00ff00"}, {0.6, "#00ffff"}, {0.8, "#0000ff"}, {1, "#ff00ff"},
+		},
+		ColorSpace: ColorSpaceHSL,
+	},
+	// viridis is the perceptually uniform colormap of matplotlib, sampled at ten points.
+	"viridis": {
+		Stops: []PaletteStop{
+			{0, "#440154"}, {1.0 / 9, "#482878"}, {2.0 / 9, "#3e4989"}, {3.0 / 9, "#31688e"}, {4.0 / 9, "#26828e"},
+			{5.0 / 9, "#1f9e89"}, {6.0 / 9, "#35b779"}, {7.0 / 9, "#6ece58"}, {8.0 / 9, "#b5de2b"}, {1, "#fde725"},
+		},
+		ColorSpace: ColorSpaceOKLab,
+	},
+}
+
+// PaletteNames returns the names of the palettes in the built-in library, in alphabetical order.
+func PaletteNames() []string {
+	names := make([]string, 0, len(paletteLibrary))
+	for name := range paletteLibrary {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PaletteSwatches renders every named palette as a labelled strip of the given width, one below the other.
+func PaletteSwatches(names []string, width int) (*image.RGBA, error) {
+	if width <= 0 {
+		return nil, fmt.Errorf("invalid width (%d)", width)
+	}
+	labelWidth := 0
+	for _, name := range names {
+		if _, ok := paletteLibrary[name]; !ok {
+			return nil, fmt.Errorf("unknown palette (%s)", name)
+		}
+		if w := textSize(name, 1).X; w > labelWidth {
+			labelWidth = w
+		}
+	}
+	rowHeight := swatchHeight + swatchMargin
+	img := image.NewRGBA(image.Rect(0, 0, labelWidth+width+3*swatchMargin, len(names)*rowHeight+swatchMargin))
+	draw.Draw(img, img.Rect, image.NewUniform(color.Black), image.Point{}, draw.Src)
+	for i, name := range names {
+		top := swatchMargin + i*rowHeight
+		drawText(img, image.Pt(swatchMargin, top+(swatchHeight-glyphHeight)/2), name, color.White, 1)
+		g := paletteLibrary[name].gradient()
+		left := labelWidth + 2*swatchMargin
+		for x := 0; x < width; x++ {
+			position := 1.0
+			if width > 1 {
+				position = float64(x) / float64(width-1)
+			}
+			column := image.Rect(left+x, top, left+x+1, top+swatchHeight)
+			draw.Draw(img, column, image.NewUniform(g.At(position)), image.Point{}, draw.Src)
+		}
+	}
+	return img, nil
+}
@@ -0,0 +1,26 @@
+package mandelbrot
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestPalettePresets(t *testing.T) {
+	for _, name := range PaletteNames() {
+		t.Run(name, func(t *testing.T) {
+			palette, err := PaletteConfig{Preset: name}.Palette(0)
+			require.NoError(t, err)
+			require.Len(t, palette, defaultPaletteSize)
+		})
+	}
+	require.EqualError(t, PaletteConfig{Preset: "plaid"}.Validate(), "unknown Preset (plaid)")
+	require.EqualError(t, PaletteConfig{Preset: "fire", Stops: []PaletteStop{{0, "#000"}}}.Validate(), "only one of Stops, File and Preset can be set")
+}
+
+func TestPaletteSwatches(t *testing.T) {
+	img, err := PaletteSwatches([]string{"fire", "ice"}, 100)
+	require.NoError(t, err)
+	require.Equal(t, 2*(swatchHeight+swatchMargin)+swatchMargin, img.Rect.Dy())
+	_, err = PaletteSwatches([]string{"plaid"}, 100)
+	require.Error(t, err)
+}
@@ -0,0 +1,247 @@
+package mandelbrot
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+)
+
+const (
+	TransferLinear = "linear"
+	TransferSqrt   = "sqrt"
+	// TransferLog uses the logarithm of the iteration count, which spreads the colors of deep zooms more evenly.
+	TransferLog   = "log"
+	TransferPower = "power"
+	// TransferCyclic runs through the palette every Period iterations.
+	TransferCyclic = "cyclic"
+)
+
+// ColorMapping controls how the escape value of a pixel is mapped onto the palette.
+// The zero value maps linearly, as Render always did.
+type ColorMapping struct {
+	// Transfer is the transfer function; one of "linear" (the default), "sqrt", "log", "power" or "cyclic".
+	Transfer string `json:",omitempty"`
+	// Exponent is the exponent of the power transfer function; it defaults to 2.
+	Exponent float64 `json:",omitempty"`
+	// Period is the number of iterations per run through the palette for the cyclic transfer function;
+	// it defaults to the number of palette entries.
+	Period float64 `json:",omitempty"`
+	// Density multiplies the escape value before the transfer function, so that the colors change faster
+	// away from the set; it defaults to 1.
+	Density float64 `json:",omitempty"`
+	// Offset shifts the palette, as a fraction of one run through it.
+	// It applies on top of the Offset of the PaletteConfig, which shifts the gradient within the palette.
+	Offset float64 `json:",omitempty"`
+	// Repeat is the number of runs through the palette; it defaults to 1.
+	// It multiplies the Repeat of the PaletteConfig, which repeats the gradient within the palette.
+	Repeat float64 `json:",omitempty"`
+	// Interior is the color of points that never escape, as a hex color. It defaults to the last palette entry,
+	// which it replaces in paletted output. It cannot be combined with an Interior in a PaletteConfig.
+	Interior string `json:",omitempty"`
+	// set records the fields that are present in the JSON the mapping was decoded from,
+	// so that a path element can set a field back to zero.
+	set mappingFields
+}
+
+// mappingFields has a bit for every field of a ColorMapping.
+type mappingFields uint8
+
+const (
+	mappingTransfer mappingFields = 1 << iota
+	mappingExponent
+	mappingPeriod
+	mappingDensity
+	mappingOffset
+	mappingRepeat
+	mappingInterior
+)
+
+// UnmarshalJSON decodes the mapping, and records which fields are present.
+func (m *ColorMapping) UnmarshalJSON(b []byte) error {
+	type plain ColorMapping
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, (*plain)(m)); err != nil {
+		return err
+	}
+	names := map[string]mappingFields{
+		"transfer": mappingTransfer,
+		"exponent": mappingExponent,
+		"period":   mappingPeriod,
+		"density":  mappingDensity,
+		"offset":   mappingOffset,
+		"repeat":   mappingRepeat,
+		"interior": mappingInterior,
+	}
+	m.set = 0
+	for name := range fields {
+		// Like the decoder itself, match the field names regardless of case.
+		m.set |= names[strings.ToLower(name)]
+	}
+	return nil
+}
+
+func (m ColorMapping) Validate() error {
+	switch m.Transfer {
+	case "", TransferLinear, TransferSqrt, TransferLog, TransferPower, TransferCyclic:
+	default:
+		return fmt.Errorf("invalid Transfer (%s)", m.Transfer)
+	}
+	if m.Exponent < 0 {
+		return fmt.Errorf("invalid Exponent (%f)", m.Exponent)
+	}
+	if m.Period < 0 {
+		return fmt.Errorf("invalid Period (%f)", m.Period)
+	}
+	if m.Density < 0 {
+		return fmt.Errorf("invalid Density (%f)", m.Density)
+	}
+	if m.Repeat < 0 {
+		return fmt.Errorf("invalid Repeat (%f)", m.Repeat)
+	}
+	if m.Interior != "" {
+		if _, err := ParseHexColor(m.Interior); err != nil {
+			return fmt.Errorf("interior: %w", err)
+		}
+	}
+	return nil
+}
+
+// withDefaults fills in the default of every unset field, so that mappings can be interpolated.
+// The result no longer records which fields were present in its JSON, so that equal mappings compare equal.
+func (m ColorMapping) withDefaults() ColorMapping {
+	m.set = 0
+	if m.Transfer == "" {
+		m.Transfer = TransferLinear
+	}
+	if m.Exponent == 0 {
+		m.Exponent = 2
+	}
+	if m.Density == 0 {
+		m.Density = 1
+	}
+	if m.Repeat == 0 {
+		m.Repeat = 1
+	}
+	return m
+}
+
+// merge returns the mapping with every field that m sets replaced by its value in m.
+// A field is set if it is not zero, or if it is present in the JSON m was decoded from;
+// fields that are not set keep the value of the mapping they are merged onto.
+func (m ColorMapping) merge(onto ColorMapping) ColorMapping {
+	if m.Transfer != "" || m.set&mappingTransfer != 0 {
+		onto.Transfer = m.Transfer
+	}
+	if m.Exponent != 0 || m.set&mappingExponent != 0 {
+		onto.Exponent = m.Exponent
+	}
+	if m.Period != 0 || m.set&mappingPeriod != 0 {
+		onto.Period = m.Period
+	}
+	if m.Density != 0 || m.set&mappingDensity != 0 {
+		onto.Density = m.Density
+	}
+	if m.Offset != 0 || m.set&mappingOffset != 0 {
+		onto.Offset = m.Offset
+	}
+	if m.Repeat != 0 || m.set&mappingRepeat != 0 {
+		onto.Repeat = m.Repeat
+	}
+	if m.Interior != "" || m.set&mappingInterior != 0 {
+		onto.Interior = m.Interior
+	}
+	return onto
+}
+
+// position maps the escape value of a pixel that escaped to a position between 0 and 1 in the palette.
+// gradientSize is the number of palette entries, excluding the interior color.
+func (m ColorMapping) position(severity float64, maxIterations int, gradientSize int) float64 {
+	m = m.withDefaults()
+	v := math.Min(1, severity*m.Density)
+	var t float64
+	switch m.Transfer {
+	case TransferSqrt:
+		t = math.Sqrt(v)
+	case TransferLog:
+		t = math.Log1p(v*float64(maxIterations)) / math.Log1p(float64(maxIterations))
+	case TransferPower:
+		t = math.Pow(v, m.Exponent)
+	case TransferCyclic:
+		period := m.Period
+		if period == 0 {
+			period = float64(gradientSize)
+		}
+		t = v * float64(maxIterations) / period
+		t -= math.Floor(t)
+	default:
+		t = v
+	}
+	return wrapPosition(t*m.Repeat + m.Offset)
+}
+
+// interior returns the interior color, or the last palette entry if none is set.
+func (m ColorMapping) interior(palette color.Palette) color.Color {
+	if m.Interior != "" {
+		c, err := ParseHexColor(m.Interior)
+		if err == nil {
+			return c
+		}
+	}
+	return palette[len(palette)-1]
+}
+
+// withInterior returns the palette with its last entry replaced by the interior color, if one is set.
+func (m ColorMapping) withInterior(palette color.Palette) color.Palette {
+	if m.Interior == "" || len(palette) == 0 {
+		return palette
+	}
+	p := make(color.Palette, len(palette))
+	copy(p, palette)
+	p[len(p)-1] = m.interior(palette)
+	return p
+}
+
+// lerpOptional interpolates, unless either value is unset; then it switches halfway.
+func lerpOptional(from, to, u float64) float64 {
+	if from == 0 || to == 0 {
+		if u < 0.5 {
+			return from
+		}
+		return to
+	}
+	return lerp(from, to, u)
+}
+
+// lerpMapping interpolates the numeric settings and the interior color;
+// the transfer function switches halfway.
+func lerpMapping(from, to ColorMapping, u float64) ColorMapping {
+	if from == to {
+		return to
+	}
+	from, to = from.withDefaults(), to.withDefaults()
+	m := ColorMapping{
+		Transfer: to.Transfer,
+		Exponent: lerp(from.Exponent, to.Exponent, u),
+		Period:   lerpOptional(from.Period, to.Period, u),
+		Density:  lerp(from.Density, to.Density, u),
+		Offset:   lerp(from.Offset, to.Offset, u),
+		Repeat:   lerp(from.Repeat, to.Repeat, u),
+		Interior: to.Interior,
+	}
+	if u < 0.5 {
+		m.Transfer = from.Transfer
+	}
+	if from.Interior != "" && to.Interior != "" {
+		fromInterior, _ := ParseHexColor(from.Interior)
+		toInterior, _ := ParseHexColor(to.Interior)
+		m.Interior = HexColor(mixColors(ColorSpaceOKLab, fromInterior, toInterior, u))
+	} else if u < 0.5 {
+		m.Interior = from.Interior
+	}
+	return m
+}
@@ -0,0 +1,120 @@
+package mandelbrot
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestColorMappingPosition(t *testing.T) {
+	var tests = []struct {
+		desc     string
+		mapping  ColorMapping
+		severity float64
+		result   float64
+	}{
+		{desc: "default", severity: 0.25, result: 0.25},
+		{desc: "sqrt", mapping: ColorMapping{Transfer: TransferSqrt}, severity: 0.25, result: 0.5},
+		{desc: "power", mapping: ColorMapping{Transfer: TransferPower, Exponent: 3}, severity: 0.5, result: 0.125},
+		{desc: "log", mapping: ColorMapping{Transfer: TransferLog}, severity: 0.0904987562112089, result: 0.5},
+		{desc: "cyclic", mapping: ColorMapping{Transfer: TransferCyclic, Period: 40}, severity: 0.5, result: 0.25},
+		{desc: "cyclic default period", mapping: ColorMapping{Transfer: TransferCyclic}, severity: 0.05, result: 0.5},
+		{desc: "density", mapping: ColorMapping{Density: 2}, severity: 0.25, result: 0.5},
+		{desc: "density saturates", mapping: ColorMapping{Density: 4}, severity: 0.5, result: 1},
+		{desc: "repeat", mapping: ColorMapping{Repeat: 3}, severity: 0.5, result: 0.5},
+		{desc: "offset wraps", mapping: ColorMapping{Offset: 0.5}, severity: 0.75, result: 0.25},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			require.NoError(t, test.mapping.Validate())
+			// 100 iterations, and 10 gradient entries.
+			require.InDelta(t, test.result, test.mapping.position(test.severity, 100, 10), 1e-9)
+		})
+	}
+	require.Error(t, ColorMapping{Transfer: "cubic"}.Validate())
+	require.Error(t, ColorMapping{Interior: "red"}.Validate())
+}
+
+func TestLerpMapping(t *testing.T) {
+	from := ColorMapping{Transfer: TransferSqrt, Interior: "#000000"}
+	to := ColorMapping{Transfer: TransferLog, Density: 3, Offset: 0.5, Interior: "#ffffff"}
+	m := lerpMapping(from, to, 0.25)
+	require.Equal(t, TransferSqrt, m.Transfer)
+	require.Equal(t, 1.5, m.Density)
+	require.Equal(t, 0.125, m.Offset)
+	m = lerpMapping(from, to, 0.5)
+	require.Equal(t, TransferLog, m.Transfer)
+	require.Equal(t, "#636363", m.Interior)
+}
+
+func TestMergeMapping(t *testing.T) {
+	inherited := ColorMapping{Transfer: TransferSqrt, Density: 2, Offset: 0.25, Interior: "#ff0000"}.withDefaults()
+	m := ColorMapping{Density: 3, Repeat: 2}.merge(inherited)
+	require.Equal(t, ColorMapping{Transfer: TransferSqrt, Exponent: 2, Density: 3, Offset: 0.25, Repeat: 2, Interior: "#ff0000"}, m)
+}
+
+func TestMergeMappingZero(t *testing.T) {
+	var back ColorMapping
+	require.NoError(t, json.Unmarshal([]byte(`{"Offset": 0, "period": 0, "Interior": ""}`), &back))
+	cfg := AnimationConfig{
+		MaxIterations: 50,
+		Mapping:       ColorMapping{Transfer: TransferCyclic, Period: 5, Offset: 0.3, Interior: "#ff0000"},
+		Path: []AnimationConfigPathElement{
+			{Zoom: 1, TargetX: 0.5, TargetY: 0.5},
+			{Zoom: 1, TargetX: 0.5, TargetY: 0.5, Duration: time.Second, Mapping: &back},
+		},
+	}
+	keyframes, err := cfg.keyframes(DefaultPalette())
+	require.NoError(t, err)
+	m := *keyframes[1].Mapping
+	require.Equal(t, ColorMapping{Transfer: TransferCyclic, Exponent: 2, Density: 1, Repeat: 1}, m, "present fields are set back to zero")
+
+	cameraPath, err := NewCameraPath(keyframes, cfg.Interpolation)
+	require.NoError(t, err)
+	require.InDelta(t, 0.15, cameraPath.At(500*time.Millisecond).Mapping.Offset, 1e-9)
+	require.Equal(t, 0.0, cameraPath.At(time.Second).Mapping.Offset)
+}
+
+func TestAnimateMapping(t *testing.T) {
+	cfg := AnimationConfig{
+		Width:         8,
+		Height:        6,
+		FPS:           10,
+		MaxIterations: 50,
+		Mapping:       ColorMapping{Interior: "#ff0000"},
+		Path: []AnimationConfigPathElement{
+			{Zoom: 1, TargetX: 0.5, TargetY: 0.5},
+			{Zoom: 1, TargetX: 0.5, TargetY: 0.5, Duration: 400 * time.Millisecond, Mapping: &ColorMapping{Density: 3}},
+		},
+	}
+	palette := Gradient(color.RGBA{B: 255, A: 255}, color.Black, 16)
+	var sink memorySink
+	require.NoError(t, Animate(cfg, palette, &sink))
+	require.Len(t, sink.frames, 4)
+	first := sink.frames[0].Image.(*image.Paletted)
+	require.Equal(t, color.RGBA{R: 255, A: 255}, first.Palette[15], "interior color replaces the last entry")
+	require.Equal(t, 2.0, sink.frames[2].Render.Mapping.Density, "density is interpolated")
+	last := sink.frames[3].Image.(*image.Paletted)
+	require.Equal(t, color.RGBA{R: 255, A: 255}, last.Palette[15], "the last element keeps the interior color")
+	require.NotEqual(t, first.Pix, last.Pix)
+}
+
+func TestValidateInterior(t *testing.T) {
+	cfg := AnimationConfig{
+		Width:         8,
+		Height:        6,
+		FPS:           10,
+		MaxIterations: 50,
+		Palette:       &PaletteConfig{Preset: "fire", Interior: "#000"},
+		Path: []AnimationConfigPathElement{
+			{Zoom: 1, TargetX: 0.5, TargetY: 0.5},
+			{Zoom: 2, TargetX: 0.5, TargetY: 0.5, Duration: time.Second, Mapping: &ColorMapping{Density: 2}},
+		},
+	}
+	require.NoError(t, cfg.Validate())
+	cfg.Path[1].Mapping.Interior = "#fff"
+	require.EqualError(t, cfg.Validate(), "an Interior color can be set in palettes or in mappings, but not in both")
+}
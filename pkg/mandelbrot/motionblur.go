@@ -0,0 +1,83 @@
+package mandelbrot
+
+import (
+	"fmt"
+	"image"
+	"time"
+)
+
+// defaultShutterAngle exposes every frame for half of its duration, as is common in film.
+const defaultShutterAngle = 180
+
+// motionBlurSamples spreads MotionBlurSamples sub-frames evenly over the time the shutter is open.
+// The exposure is centered on t; see motionBlurTimes.
+func (cfg AnimationConfig) motionBlurSamples(cameraPath *CameraPath, palettes *paletteTrack, t, delay, end time.Duration) ([]frameSpec, error) {
+	shutterAngle := cfg.ShutterAngle
+	if shutterAngle == 0 {
+		shutterAngle = defaultShutterAngle
+	}
+	times := motionBlurTimes(cfg.MotionBlurSamples, t, time.Duration(float64(delay)*shutterAngle/360), end)
+	samples := make([]frameSpec, len(times))
+	for i, subT := range times {
+		renderCfg, err := cfg.renderConfig(cameraPath.At(subT))
+		if err != nil {
+			return nil, fmt.Errorf("determining render config (sample %d): %w", i, err)
+		}
+		samples[i] = frameSpec{
+			Render:  renderCfg,
+			Palette: cfg.paletteAt(palettes, subT),
+		}
+	}
+	return samples, nil
+}
+
+// motionBlurTimes returns the timeline positions of n sub-frames, each in the middle of an equal part of the exposure
+// centered on t. Positions before the start or after the end of the animation are clamped to it.
+func motionBlurTimes(n int, t, exposure, end time.Duration) []time.Duration {
+	times := make([]time.Duration, n)
+	for i := range times {
+		subT := t + time.Duration((float64(i)+0.5)/float64(n)*float64(exposure)) - exposure/2
+		if subT < 0 {
+			subT = 0
+		}
+		if subT > end {
+			subT = end
+		}
+		times[i] = subT
+	}
+	return times
+}
+
+// renderMotionBlurred renders the sub-frames in true color, and averages them into a single frame.
+func (cfg AnimationConfig) renderMotionBlurred(samples []frameSpec) (*image.RGBA, error) {
+	bounds := image.Rect(0, 0, cfg.Width, cfg.Height)
+	sums := make([]uint32, 4*cfg.Width*cfg.Height)
+	sub := image.NewRGBA(bounds)
+	for i, sample := range samples {
+		if err := RenderRGBA(sample.Render, sub, sample.Palette); err != nil {
+			return nil, fmt.Errorf("rendering (sample %d): %w", i, err)
+		}
+		for j, v := range sub.Pix {
+			sums[j] += uint32(v)
+		}
+	}
+	img := image.NewRGBA(bounds)
+	n := uint32(len(samples))
+	for j, sum := range sums {
+		img.Pix[j] = uint8((sum + n/2) / n)
+	}
+	return img, nil
+}
+
+// sameSamples reports whether two sets of sub-frames average into the same frame.
+func sameSamples(a, b []frameSpec) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Render != b[i].Render || !samePalette(a[i].Palette, b[i].Palette) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,145 @@
+package mandelbrot
+
+import (
+	"github.com/stretchr/testify/require"
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestMotionBlurTimes(t *testing.T) {
+	const ms = time.Millisecond
+	var tests = []struct {
+		desc     string
+		n        int
+		t        time.Duration
+		exposure time.Duration
+		times    []time.Duration
+	}{
+		{
+			desc:     "centered on the frame",
+			n:        4,
+			t:        100 * ms,
+			exposure: 40 * ms,
+			times:    []time.Duration{85 * ms, 95 * ms, 105 * ms, 115 * ms},
+		},
+		{
+			desc:     "clamped at the start",
+			n:        2,
+			t:        0,
+			exposure: 40 * ms,
+			times:    []time.Duration{0, 10 * ms},
+		},
+		{
+			desc:     "clamped at the path end",
+			n:        4,
+			t:        500 * ms,
+			exposure: 40 * ms,
+			times:    []time.Duration{485 * ms, 495 * ms, 500 * ms, 500 * ms},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			require.Equal(t, test.times, motionBlurTimes(test.n, test.t, test.exposure, 500*ms))
+		})
+	}
+}
+
+func TestMotionBlurSamplesShutterAngle(t *testing.T) {
+	cfg := AnimationConfig{
+		MaxIterations:     50,
+		MotionBlurSamples: 2,
+		ShutterAngle:      360,
+		Path: []AnimationConfigPathElement{
+			{Zoom: 1, TargetX: 0.5, TargetY: 0.5},
+			{Zoom: 3, TargetX: 0.5, TargetY: 0.5, Duration: time.Second},
+		},
+	}
+	keyframes, err := cfg.keyframes(DefaultPalette())
+	require.NoError(t, err)
+	cameraPath, err := NewCameraPath(keyframes, cfg.Interpolation)
+	require.NoError(t, err)
+	palettes := newPaletteTrack(cameraPath)
+	// A 360 degree shutter spreads the sub-frames over the whole 200ms frame.
+	samples, err := cfg.motionBlurSamples(cameraPath, palettes, 500*time.Millisecond, 200*time.Millisecond, time.Second)
+	require.NoError(t, err)
+	require.Len(t, samples, 2)
+	require.InDelta(t, 1.9, samples[0].Render.Zoom, 1e-9)
+	require.InDelta(t, 2.1, samples[1].Render.Zoom, 1e-9)
+}
+
+// solidSample renders every pixel in a single color, regardless of the view.
+func solidSample(c color.RGBA) frameSpec {
+	return frameSpec{
+		Render:  RenderConfig{MaxIterations: 10, Zoom: 1, TargetX: 0.5, TargetY: 0.5},
+		Palette: color.Palette{c, c, c},
+	}
+}
+
+func TestRenderMotionBlurred(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	purple := color.RGBA{R: 128, B: 128, A: 255}
+	cfg := AnimationConfig{Width: 4, Height: 3}
+	spec := frameSpec{
+		Palette: color.Palette{red, blue, purple, color.RGBA{A: 255}},
+		Samples: []frameSpec{solidSample(red), solidSample(blue)},
+	}
+
+	trueColor := &animator{cfg: cfg, trueColor: true}
+	img, err := trueColor.renderFrame(spec)
+	require.NoError(t, err)
+	rgbaImg, ok := img.(*image.RGBA)
+	require.True(t, ok, "true color frames are RGBA, got %T", img)
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 4; x++ {
+			require.Equal(t, purple, rgbaImg.RGBAAt(x, y), "pixel (%d,%d)", x, y)
+		}
+	}
+
+	paletted := &animator{cfg: cfg}
+	img, err = paletted.renderFrame(spec)
+	require.NoError(t, err)
+	pm, ok := img.(*image.Paletted)
+	require.True(t, ok, "paletted frames are paletted, got %T", img)
+	require.Equal(t, spec.Palette, pm.Palette)
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 4; x++ {
+			require.Equal(t, uint8(2), pm.ColorIndexAt(x, y), "pixel (%d,%d)", x, y)
+		}
+	}
+}
+
+func TestRenderMotionBlurredHold(t *testing.T) {
+	cfg := AnimationConfig{
+		Width:             8,
+		Height:            6,
+		FPS:               10,
+		MaxIterations:     50,
+		MotionBlurSamples: 3,
+		Path: []AnimationConfigPathElement{
+			{Zoom: 1, TargetX: 0.5, TargetY: 0.5},
+			{Zoom: 4, TargetX: 0.4, TargetY: 0.4, Duration: 300 * time.Millisecond, Hold: 500 * time.Millisecond},
+			{Zoom: 1, TargetX: 0.5, TargetY: 0.5, Duration: 300 * time.Millisecond},
+		},
+	}
+	for _, trueColor := range []bool{false, true} {
+		a, err := newAnimator(cfg, DefaultPalette(), trueColor)
+		require.NoError(t, err)
+		var images []image.Image
+		for _, at := range []time.Duration{300, 400, 500, 600, 700} {
+			spec, err := a.frameSpec(at * time.Millisecond)
+			require.NoError(t, err)
+			img, err := a.renderFrame(spec)
+			require.NoError(t, err)
+			images = append(images, img)
+		}
+		// The exposure of the first frame of the hold starts while the camera still moves.
+		require.NotSame(t, images[0], images[1], "true color %v", trueColor)
+		// The rest of the hold shows the same view, which is not rendered again.
+		for i := 2; i < len(images); i++ {
+			require.Same(t, images[1], images[i], "true color %v, frame %d", trueColor, i)
+		}
+	}
+}
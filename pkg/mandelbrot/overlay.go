@@ -0,0 +1,112 @@
+package mandelbrot
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	OverlayTopLeft     = "top-left"
+	OverlayTopRight    = "top-right"
+	OverlayBottomLeft  = "bottom-left"
+	OverlayBottomRight = "bottom-right"
+)
+
+// Overlay is text drawn onto every frame, in white on a black box.
+type Overlay struct {
+	// Text is drawn as is, apart from these placeholders: {zoom}, {x} and {y} (the center of the view),
+	// {iterations}, {frame} (starting at 1) and {frames}. A newline starts a new line.
+	Text string
+	// Position is one of "top-left" (the default), "top-right", "bottom-left" or "bottom-right".
+	Position string
+	// Scale enlarges the font by a whole factor; it defaults to 1.
+	Scale int
+}
+
+// overlayInfo contains the values substituted into overlay text.
+type overlayInfo struct {
+	Render RenderConfig
+	// Frame is the index of the frame in the full animation, starting at 0.
+	Frame  int
+	Frames int
+}
+
+func (o Overlay) Validate() error {
+	switch o.Position {
+	case "", OverlayTopLeft, OverlayTopRight, OverlayBottomLeft, OverlayBottomRight:
+	default:
+		return fmt.Errorf("invalid Position (%s)", o.Position)
+	}
+	if o.Scale < 0 {
+		return fmt.Errorf("invalid Scale (%d)", o.Scale)
+	}
+	return nil
+}
+
+// text returns the overlay text with its placeholders filled in.
+func (o Overlay) text(info overlayInfo) string {
+	cx, cy, _ := cameraView(CameraState{
+		Zoom:    info.Render.Zoom,
+		TargetX: info.Render.TargetX,
+		TargetY: info.Render.TargetY,
+	})
+	// Deeper zooms need more digits to tell neighbouring views apart.
+	digits := 4 + int(math.Ceil(math.Log10(math.Max(1, info.Render.Zoom))))
+	return strings.NewReplacer(
+		"{zoom}", strconv.FormatFloat(info.Render.Zoom, 'g', 6, 64),
+		"{x}", strconv.FormatFloat(minX+cx, 'f', digits, 64),
+		"{y}", strconv.FormatFloat(minY+cy, 'f', digits, 64),
+		"{iterations}", strconv.Itoa(info.Render.MaxIterations),
+		"{frame}", strconv.Itoa(info.Frame+1),
+		"{frames}", strconv.Itoa(info.Frames),
+	).Replace(o.Text)
+}
+
+// drawOverlays returns a copy of img with the overlays drawn onto it, so that img itself can still be reused.
+// Paletted images stay paletted, using the palette entries closest to white and black.
+func drawOverlays(img image.Image, overlays []Overlay, info overlayInfo) image.Image {
+	var dst draw.Image
+	switch src := img.(type) {
+	case *image.Paletted:
+		pm := image.NewPaletted(src.Rect, src.Palette)
+		copy(pm.Pix, src.Pix)
+		dst = pm
+	default:
+		rgba := image.NewRGBA(img.Bounds())
+		draw.Draw(rgba, rgba.Rect, img, img.Bounds().Min, draw.Src)
+		dst = rgba
+	}
+	for _, o := range overlays {
+		scale := o.Scale
+		if scale == 0 {
+			scale = 1
+		}
+		text := o.text(info)
+		size := textSize(text, scale)
+		if size == (image.Point{}) {
+			continue
+		}
+		padding := scale
+		margin := 2 * scale
+		bounds := dst.Bounds()
+		box := image.Rectangle{Max: size.Add(image.Pt(2*padding, 2*padding))}
+		switch o.Position {
+		case OverlayTopRight:
+			box = box.Add(image.Pt(bounds.Max.X-margin-box.Dx(), bounds.Min.Y+margin))
+		case OverlayBottomLeft:
+			box = box.Add(image.Pt(bounds.Min.X+margin, bounds.Max.Y-margin-box.Dy()))
+		case OverlayBottomRight:
+			box = box.Add(image.Pt(bounds.Max.X-margin-box.Dx(), bounds.Max.Y-margin-box.Dy()))
+		default:
+			box = box.Add(image.Pt(bounds.Min.X+margin, bounds.Min.Y+margin))
+		}
+		draw.Draw(dst, box.Intersect(bounds), image.NewUniform(color.Black), image.Point{}, draw.Src)
+		drawText(dst, box.Min.Add(image.Pt(padding, padding)), text, color.White, scale)
+	}
+	return dst
+}
@@ -0,0 +1,41 @@
+package mandelbrot
+
+import (
+	"github.com/stretchr/testify/require"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestOverlayText(t *testing.T) {
+	info := overlayInfo{
+		Render: RenderConfig{MaxIterations: 250, Zoom: 1, TargetX: 0.5, TargetY: 0.5},
+		Frame:  9,
+		Frames: 40,
+	}
+	o := Overlay{Text: "ZOOM {zoom} AT {x},{y}\n{iterations} ITERATIONS, FRAME {frame}/{frames}"}
+	require.Equal(t, "ZOOM 1 AT -0.7650,0.0000\n250 ITERATIONS, FRAME 10/40", o.text(info))
+}
+
+func TestDrawOverlays(t *testing.T) {
+	palette := color.Palette{color.RGBA{B: 255, A: 255}, color.RGBA{R: 250, G: 250, B: 250, A: 255}, color.Black}
+	src := image.NewPaletted(image.Rect(0, 0, 40, 20), palette)
+	overlays := []Overlay{
+		{Text: "{frame}", Position: OverlayBottomRight},
+	}
+	img := drawOverlays(src, overlays, overlayInfo{Frame: 0, Frames: 1})
+	for _, v := range src.Pix {
+		require.Equal(t, uint8(0), v, "source must be unchanged")
+	}
+	pm, ok := img.(*image.Paletted)
+	require.True(t, ok)
+	counts := make(map[uint8]int)
+	for _, v := range pm.Pix {
+		counts[v]++
+	}
+	// The glyph for 1 has 10 pixels, drawn in the closest color to white, in a box of the closest color to black.
+	require.Equal(t, 10, counts[1])
+	boxSize := textSize("1", 1).Add(image.Pt(2, 2))
+	require.Equal(t, boxSize.X*boxSize.Y-10, counts[2])
+	require.Equal(t, color.Black, pm.At(37, 17))
+}
@@ -0,0 +1,276 @@
+package mandelbrot
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultPaletteSize is the number of palette entries when no size is configured; the most a GIF can hold.
+const defaultPaletteSize = 256
+
+// PaletteConfig describes a palette as a gradient, in the animation config.
+type PaletteConfig struct {
+	Stops []PaletteStop
+	// File is a palette file (.map, .gpl, .ugr, .ucl or .csv) to read the Stops from, instead of listing them.
+	// A relative path is relative to the animation config file.
+	File string `json:",omitempty"`
+	// Name selects a gradient from a collection in File; by default the first one is used.
+	Name string `json:",omitempty"`
+	// Preset selects a palette from the built-in library by name, such as "viridis", instead of listing the Stops.
+	Preset string `json:",omitempty"`
+	// ColorSpace selects the space in which stops are interpolated; it defaults to OKLab.
+	ColorSpace string `json:",omitempty"`
+	// Size is the number of palette entries, including the interior color.
+	// It defaults to 256, or to the size of the animation palette for a palette on a path element.
+	Size int `json:",omitempty"`
+	// Interior is the color of points inside the set, as a hex color; it defaults to black.
+	// It cannot be combined with an Interior in a ColorMapping.
+	Interior string `json:",omitempty"`
+	// Repeat is the number of times the gradient runs through the palette entries; it defaults to 1.
+	// The Repeat of the ColorMapping runs through the entries in turn, so the two multiply.
+	Repeat float64 `json:",omitempty"`
+	// Offset shifts the gradient within the palette entries, as a fraction of one repetition.
+	// The Offset of the ColorMapping shifts the entries in turn, and can change from keyframe to keyframe.
+	Offset float64 `json:",omitempty"`
+}
+
+// PaletteStop is a hex color, such as "#1e90ff", at a position between 0 and 1 in the gradient.
+type PaletteStop struct {
+	Position float64
+	Color    string
+}
+
+// DefaultPalette is used when the animation config does not define a palette.
+func DefaultPalette() color.Palette {
+	blue := color.RGBA{
+		B: 255,
+		A: 255,
+	}
+	return Gradient(blue, color.Black, defaultPaletteSize)
+}
+
+func (pc PaletteConfig) Validate() error {
+	if pc.File != "" && len(pc.Stops) == 0 {
+		return fmt.Errorf("palette file (%s) is not loaded", pc.File)
+	}
+	if pc.Preset != "" {
+		if len(pc.Stops) > 0 || pc.File != "" {
+			return fmt.Errorf("only one of Stops, File and Preset can be set")
+		}
+		if _, ok := paletteLibrary[pc.Preset]; !ok {
+			return fmt.Errorf("unknown Preset (%s)", pc.Preset)
+		}
+	} else if len(pc.Stops) == 0 {
+		return fmt.Errorf("at least one stop is required")
+	}
+	for i, stop := range pc.Stops {
+		if _, err := ParseHexColor(stop.Color); err != nil {
+			return fmt.Errorf("stop (%d): %w", i, err)
+		}
+		if stop.Position < 0 || stop.Position > 1 {
+			return fmt.Errorf("stop (%d): position (%f) is outside of 0-1", i, stop.Position)
+		}
+		if i > 0 && stop.Position < pc.Stops[i-1].Position {
+			return fmt.Errorf("stop (%d): position (%f) is before the previous stop (%f)", i, stop.Position, pc.Stops[i-1].Position)
+		}
+	}
+	if pc.ColorSpace != "" {
+		if err := validateColorSpace(pc.ColorSpace); err != nil {
+			return err
+		}
+	}
+	if pc.Size < 0 || pc.Size == 1 || pc.Size > 256 {
+		return fmt.Errorf("invalid Size (%d)", pc.Size)
+	}
+	if pc.Interior != "" {
+		if _, err := ParseHexColor(pc.Interior); err != nil {
+			return fmt.Errorf("interior: %w", err)
+		}
+	}
+	if pc.Repeat < 0 {
+		return fmt.Errorf("invalid Repeat (%f)", pc.Repeat)
+	}
+	return nil
+}
+
+// LoadFile reads the Stops from File, if it is set; a relative File is resolved against dir.
+func (pc *PaletteConfig) LoadFile(dir string) error {
+	if pc.File == "" {
+		return nil
+	}
+	if len(pc.Stops) > 0 || pc.Preset != "" {
+		return fmt.Errorf("only one of Stops, File and Preset can be set")
+	}
+	filePath := pc.File
+	if !filepath.IsAbs(filePath) {
+		filePath = filepath.Join(dir, filePath)
+	}
+	g, err := ReadPaletteFile(filePath, pc.Name)
+	if err != nil {
+		return fmt.Errorf("reading palette file (%s): %w", pc.File, err)
+	}
+	for _, stop := range g.Stops {
+		pc.Stops = append(pc.Stops, PaletteStop{Position: stop.Position, Color: HexColor(stop.Color)})
+	}
+	if pc.ColorSpace == "" {
+		pc.ColorSpace = g.ColorSpace
+	}
+	return nil
+}
+
+// Palette creates the palette, using defaultSize if no Size is configured.
+// The gradient fills all entries but the last, which holds the interior color.
+func (pc PaletteConfig) Palette(defaultSize int) (color.Palette, error) {
+	if err := pc.Validate(); err != nil {
+		return nil, err
+	}
+	size := pc.Size
+	if size == 0 {
+		size = defaultSize
+	}
+	if size == 0 {
+		size = defaultPaletteSize
+	}
+	g := pc.gradient()
+	repeat := pc.Repeat
+	if repeat == 0 {
+		repeat = 1
+	}
+	n := size - 1
+	p := make(color.Palette, size)
+	for i := 0; i < n; i++ {
+		position := 1.0
+		if n > 1 {
+			position = float64(i) / float64(n-1)
+		}
+		p[i] = g.At(wrapPosition(pc.Offset + repeat*position))
+	}
+	p[n] = color.RGBA{A: 255}
+	if pc.Interior != "" {
+		p[n], _ = ParseHexColor(pc.Interior)
+	}
+	return p, nil
+}
+
+// gradient converts the Stops, or the Preset, to a gradient; pc must be valid.
+func (pc PaletteConfig) gradient() StopGradient {
+	stops, space := pc.Stops, pc.ColorSpace
+	if pc.Preset != "" {
+		preset := paletteLibrary[pc.Preset]
+		stops = preset.Stops
+		if space == "" {
+			space = preset.ColorSpace
+		}
+	}
+	g := StopGradient{ColorSpace: space}
+	for _, stop := range stops {
+		c, _ := ParseHexColor(stop.Color)
+		g.Stops = append(g.Stops, GradientStop{Position: stop.Position, Color: c})
+	}
+	return g
+}
+
+// wrapPosition wraps a gradient position into 0-1, so that a repeating gradient ends each repetition
+// on its last stop, rather than jumping back to the first.
+func wrapPosition(position float64) float64 {
+	wrapped := position - math.Floor(position)
+	if wrapped == 0 && position > 0 {
+		return 1
+	}
+	return wrapped
+}
+
+// ParseHexColor parses a color in the form "#rgb", "#rrggbb" or "#rrggbbaa".
+func ParseHexColor(s string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	if len(hex) != 8 || !strings.HasPrefix(s, "#") {
+		return color.RGBA{}, fmt.Errorf("invalid color (%s)", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color (%s)", s)
+	}
+	return color.RGBAModel.Convert(color.NRGBA{
+		R: uint8(v >> 24),
+		G: uint8(v >> 16),
+		B: uint8(v >> 8),
+		A: uint8(v),
+	}).(color.RGBA), nil
+}
+
+// HexColor formats a color as "#rrggbb", or as "#rrggbbaa" if it is not opaque.
+func HexColor(c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	if n.A == 255 {
+		return fmt.Sprintf("#%02x%02x%02x", n.R, n.G, n.B)
+	}
+	return fmt.Sprintf("#%02x%02x%02x%02x", n.R, n.G, n.B, n.A)
+}
+
+// BasePalette returns the configured Palette, or the given palette if none is configured.
+// The animation functions call it themselves; a caller that needs the palette too can clear Palette
+// after resolving it, and pass the result, so that it is built only once.
+func (cfg AnimationConfig) BasePalette(palette color.Palette) (color.Palette, error) {
+	if cfg.Palette == nil {
+		return palette, nil
+	}
+	p, err := cfg.Palette.Palette(defaultPaletteSize)
+	if err != nil {
+		return nil, fmt.Errorf("creating palette: %w", err)
+	}
+	return p, nil
+}
+
+// paletteTrack follows the keyframe palettes along a camera path,
+// blending between the palettes of consecutive keyframes as the camera moves between them.
+type paletteTrack struct {
+	cameraPath *CameraPath
+}
+
+func newPaletteTrack(cameraPath *CameraPath) *paletteTrack {
+	return &paletteTrack{cameraPath: cameraPath}
+}
+
+// At returns the palette at timeline position t.
+func (pt *paletteTrack) At(t time.Duration) color.Palette {
+	keyframes := pt.cameraPath.keyframes
+	i, u := pt.cameraPath.segment(t)
+	if i == 0 || u >= 1 {
+		return keyframes[i].palette
+	}
+	from, to := keyframes[i-1].palette, keyframes[i].palette
+	if u <= 0 {
+		return from
+	}
+	if samePalette(from, to) {
+		return to
+	}
+	p := make(color.Palette, len(to))
+	for j := range p {
+		p[j] = mixColors(keyframes[i].paletteSpace, from[j], to[j], u)
+	}
+	return p
+}
+
+func samePalette(a, b color.Palette) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+package mandelbrot
+
+import (
+	"github.com/stretchr/testify/require"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestParseHexColor(t *testing.T) {
+	var tests = []struct {
+		desc   string
+		input  string
+		result color.RGBA
+		err    bool
+	}{
+		{desc: "short", input: "#f80", result: color.RGBA{R: 255, G: 136, A: 255}},
+		{desc: "long", input: "#1e90ff", result: color.RGBA{R: 30, G: 144, B: 255, A: 255}},
+		{desc: "alpha", input: "#ffffff00", result: color.RGBA{}},
+		{desc: "missing hash", input: "1e90ff", err: true},
+		{desc: "invalid digit", input: "#1e90fg", err: true},
+		{desc: "wrong length", input: "#1e90f", err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			c, err := ParseHexColor(test.input)
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.result, c)
+		})
+	}
+	require.Equal(t, "#1e90ff", HexColor(color.RGBA{R: 30, G: 144, B: 255, A: 255}))
+}
+
+func TestPaletteConfig(t *testing.T) {
+	black := color.RGBA{A: 255}
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	red := color.RGBA{R: 255, A: 255}
+	pc := PaletteConfig{
+		Stops:      []PaletteStop{{Position: 0, Color: "#000"}, {Position: 1, Color: "#fff"}},
+		ColorSpace: ColorSpaceSRGB,
+		Size:       6,
+		Interior:   "#ff0000",
+	}
+	palette, err := pc.Palette(0)
+	require.NoError(t, err)
+	require.Len(t, palette, 6)
+	require.Equal(t, black, palette[0])
+	require.Equal(t, white, palette[4])
+	require.Equal(t, red, palette[5])
+
+	// A repeating gradient ends every repetition on its last stop.
+	pc.Repeat = 2
+	palette, err = pc.Palette(0)
+	require.NoError(t, err)
+	gray := color.RGBA{R: 128, G: 128, B: 128, A: 255}
+	require.Equal(t, color.Palette{black, gray, white, gray, white, red}, palette)
+
+	pc.Stops[1].Color = "#ggg"
+	err = AnimationConfig{
+		Width:         8,
+		Height:        6,
+		FPS:           10,
+		MaxIterations: 50,
+		Path:          []AnimationConfigPathElement{{Zoom: 1, TargetX: 0.5, TargetY: 0.5, Palette: &pc}},
+	}.Validate()
+	require.EqualError(t, err, "path element (0): palette: stop (1): invalid color (#ggg)")
+}
+
+func TestPaletteTrack(t *testing.T) {
+	toWhite := &PaletteConfig{
+		Stops:      []PaletteStop{{Position: 0, Color: "#fff"}},
+		ColorSpace: ColorSpaceLinearRGB,
+	}
+	cfg := AnimationConfig{
+		Width:         8,
+		Height:        6,
+		FPS:           10,
+		MaxIterations: 50,
+		Palette: &PaletteConfig{
+			Stops: []PaletteStop{{Position: 0, Color: "#000"}},
+			Size:  4,
+		},
+		Path: []AnimationConfigPathElement{
+			{Zoom: 1, TargetX: 0.5, TargetY: 0.5},
+			{Zoom: 2, TargetX: 0.5, TargetY: 0.5, Duration: time.Second, Hold: time.Second},
+			{Zoom: 4, TargetX: 0.5, TargetY: 0.5, Duration: time.Second, Palette: toWhite},
+			{Zoom: 8, TargetX: 0.5, TargetY: 0.5, Duration: time.Second},
+		},
+	}
+	require.NoError(t, cfg.Validate())
+	base, err := cfg.BasePalette(nil)
+	require.NoError(t, err)
+	keyframes, err := cfg.keyframes(base)
+	require.NoError(t, err)
+	cameraPath, err := NewCameraPath(keyframes, cfg.Interpolation)
+	require.NoError(t, err)
+	track := newPaletteTrack(cameraPath)
+
+	black := color.RGBA{A: 255}
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	gray := color.RGBA{R: 188, G: 188, B: 188, A: 255}
+	require.Equal(t, color.Palette{black, black, black, black}, track.At(1500*time.Millisecond), "during the hold")
+	require.Equal(t, color.Palette{gray, gray, gray, black}, track.At(2500*time.Millisecond), "halfway, in linear light")
+	require.Equal(t, color.Palette{white, white, white, black}, track.At(3500*time.Millisecond), "kept after the change")
+
+	cfg.Path[2].Palette = &PaletteConfig{Stops: toWhite.Stops, Size: 8}
+	_, err = cfg.keyframes(base)
+	require.EqualError(t, err, "path element (2): palette size (8) differs from the animation palette (4)")
+}
@@ -0,0 +1,327 @@
+package mandelbrot
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// PaletteFormatMap is the Fractint .map format: one "R G B" line per color.
+	PaletteFormatMap = "map"
+	// PaletteFormatGPL is the GIMP palette format.
+	PaletteFormatGPL = "gpl"
+	// PaletteFormatUGR is the UltraFractal gradient collection format.
+	PaletteFormatUGR = "ugr"
+	// PaletteFormatUCL is an UltraFractal coloring library; only the gradients embedded in it are read,
+	// and it cannot be written.
+	PaletteFormatUCL = "ucl"
+	// PaletteFormatCSV is a plain list of hex colors, separated by commas or newlines.
+	PaletteFormatCSV = "csv"
+)
+
+// ugrPositions is the number of positions in an UltraFractal gradient, which wraps around.
+const ugrPositions = 400
+
+// PaletteFileFormat derives the palette format from the file extension.
+func PaletteFileFormat(filePath string) (string, error) {
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(filePath)), ".")
+	switch format {
+	case PaletteFormatMap, PaletteFormatGPL, PaletteFormatUGR, PaletteFormatUCL, PaletteFormatCSV:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported palette file extension (%s)", filepath.Ext(filePath))
+	}
+}
+
+// ReadPaletteFile reads a gradient from a palette file, in the format given by its extension.
+// For collections of gradients, name selects the gradient; an empty name selects the first.
+func ReadPaletteFile(filePath string, name string) (StopGradient, error) {
+	format, err := PaletteFileFormat(filePath)
+	if err != nil {
+		return StopGradient{}, err
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		return StopGradient{}, fmt.Errorf("opening file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	return ReadPalette(f, format, name)
+}
+
+// ReadPalette reads a gradient in the given format.
+// Formats that only list colors result in evenly spaced stops.
+func ReadPalette(r io.Reader, format string, name string) (StopGradient, error) {
+	var colors []color.RGBA
+	var err error
+	switch format {
+	case PaletteFormatMap:
+		colors, err = readMapPalette(r)
+	case PaletteFormatGPL:
+		colors, err = readGPLPalette(r)
+	case PaletteFormatUGR, PaletteFormatUCL:
+		return readUGRGradient(r, name)
+	case PaletteFormatCSV:
+		colors, err = readCSVPalette(r)
+	default:
+		return StopGradient{}, fmt.Errorf("unknown palette format (%s)", format)
+	}
+	if err != nil {
+		return StopGradient{}, err
+	}
+	if len(colors) == 0 {
+		return StopGradient{}, fmt.Errorf("no colors found")
+	}
+	g := StopGradient{Stops: make([]GradientStop, len(colors))}
+	for i, c := range colors {
+		position := 0.0
+		if len(colors) > 1 {
+			position = float64(i) / float64(len(colors)-1)
+		}
+		g.Stops[i] = GradientStop{Position: position, Color: c}
+	}
+	return g, nil
+}
+
+// WritePaletteFile writes the palette to a file, in the format given by its extension.
+func WritePaletteFile(filePath string, palette color.Palette) error {
+	format, err := PaletteFileFormat(filePath)
+	if err != nil {
+		return err
+	}
+	if format == PaletteFormatUCL {
+		return fmt.Errorf("writing palette format (%s) is not supported", format)
+	}
+	f, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("creating file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	if err := WritePalette(f, format, palette); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// WritePalette writes the palette in the given format, which cannot be PaletteFormatUCL.
+func WritePalette(w io.Writer, format string, palette color.Palette) error {
+	bw := bufio.NewWriter(w)
+	rgb := func(c color.Color) (int, int, int) {
+		n := color.NRGBAModel.Convert(c).(color.NRGBA)
+		return int(n.R), int(n.G), int(n.B)
+	}
+	switch format {
+	case PaletteFormatMap:
+		for _, c := range palette {
+			r, g, b := rgb(c)
+			_, _ = fmt.Fprintf(bw, "%d %d %d\n", r, g, b)
+		}
+	case PaletteFormatGPL:
+		_, _ = fmt.Fprintf(bw, "GIMP Palette\nName: brot\nColumns: 16\n#\n")
+		for _, c := range palette {
+			r, g, b := rgb(c)
+			_, _ = fmt.Fprintf(bw, "%3d %3d %3d\t%s\n", r, g, b, HexColor(c))
+		}
+	case PaletteFormatUGR:
+		_, _ = fmt.Fprintf(bw, "brot {\ngradient:\n  title=\"brot\" smooth=yes\n")
+		for i, c := range palette {
+			r, g, b := rgb(c)
+			index := i * ugrPositions / len(palette)
+			_, _ = fmt.Fprintf(bw, "  index=%d color=%d\n", index, r|g<<8|b<<16)
+		}
+		_, _ = fmt.Fprintf(bw, "opacity:\n  smooth=no index=0 opacity=255\n}\n")
+	case PaletteFormatCSV:
+		for _, c := range palette {
+			_, _ = fmt.Fprintln(bw, HexColor(c))
+		}
+	case PaletteFormatUCL:
+		return fmt.Errorf("writing palette format (%s) is not supported", format)
+	default:
+		return fmt.Errorf("unknown palette format (%s)", format)
+	}
+	return bw.Flush()
+}
+
+func readMapPalette(r io.Reader) ([]color.RGBA, error) {
+	var colors []color.RGBA
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		// Anything after the three components is a comment.
+		c, err := parseRGBFields(fields)
+		if err != nil {
+			return nil, fmt.Errorf("line (%d): %w", line, err)
+		}
+		colors = append(colors, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading: %w", err)
+	}
+	return colors, nil
+}
+
+func readGPLPalette(r io.Reader) ([]color.RGBA, error) {
+	var colors []color.RGBA
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != "GIMP Palette" {
+		return nil, fmt.Errorf("missing GIMP Palette header")
+	}
+	for line := 2; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, "Name:") || strings.HasPrefix(text, "Columns:") {
+			continue
+		}
+		// Anything after the three components is the name of the color.
+		c, err := parseRGBFields(strings.Fields(text))
+		if err != nil {
+			return nil, fmt.Errorf("line (%d): %w", line, err)
+		}
+		colors = append(colors, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading: %w", err)
+	}
+	return colors, nil
+}
+
+func parseRGBFields(fields []string) (color.RGBA, error) {
+	if len(fields) < 3 {
+		return color.RGBA{}, fmt.Errorf("expected three color components, got (%d)", len(fields))
+	}
+	var rgb [3]uint8
+	for i := range rgb {
+		v, err := strconv.ParseUint(fields[i], 10, 8)
+		if err != nil {
+			return color.RGBA{}, fmt.Errorf("invalid color component (%s)", fields[i])
+		}
+		rgb[i] = uint8(v)
+	}
+	return color.RGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 255}, nil
+}
+
+func readCSVPalette(r io.Reader) ([]color.RGBA, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV: %w", err)
+	}
+	var colors []color.RGBA
+	for _, record := range records {
+		for _, field := range record {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			c, err := ParseHexColor(field)
+			if err != nil {
+				return nil, fmt.Errorf("color (%d): %w", len(colors), err)
+			}
+			colors = append(colors, c)
+		}
+	}
+	return colors, nil
+}
+
+// readUGRGradient reads the gradient section of an entry in an UltraFractal collection:
+//
+//	name {
+//	gradient:
+//	  title="name" smooth=no
+//	  index=0 color=8716288
+//	  ...
+//	}
+//
+// Colors are stored as B<<16 | G<<8 | R. The gradient wraps around,
+// so stops are added at both ends with the color halfway through the wrap.
+func readUGRGradient(r io.Reader, name string) (StopGradient, error) {
+	type indexedColor struct {
+		index int
+		color color.RGBA
+	}
+	var entry string
+	var inGradient, found bool
+	var stops []indexedColor
+	scanner := bufio.NewScanner(r)
+lines:
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasSuffix(text, "{"):
+			entry = strings.TrimSpace(strings.TrimSuffix(text, "{"))
+			inGradient = false
+			continue
+		case text == "}":
+			if found {
+				break lines
+			}
+			inGradient = false
+			continue
+		case strings.HasSuffix(text, ":"):
+			inGradient = text == "gradient:" && (name == "" || entry == name)
+			continue
+		}
+		if found && !inGradient {
+			break lines
+		}
+		if !inGradient {
+			continue
+		}
+		fields := make(map[string]string)
+		for _, field := range strings.Fields(text) {
+			if k, v, ok := strings.Cut(field, "="); ok {
+				fields[k] = v
+			}
+		}
+		rawIndex, hasIndex := fields["index"]
+		rawColor, hasColor := fields["color"]
+		if !hasIndex || !hasColor {
+			continue
+		}
+		index, err := strconv.Atoi(rawIndex)
+		if err != nil {
+			return StopGradient{}, fmt.Errorf("line (%d): invalid index (%s)", line, rawIndex)
+		}
+		v, err := strconv.ParseUint(rawColor, 10, 32)
+		if err != nil {
+			return StopGradient{}, fmt.Errorf("line (%d): invalid color (%s)", line, rawColor)
+		}
+		found = true
+		stops = append(stops, indexedColor{
+			index: ((index % ugrPositions) + ugrPositions) % ugrPositions,
+			color: color.RGBA{R: uint8(v), G: uint8(v >> 8), B: uint8(v >> 16), A: 255},
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return StopGradient{}, fmt.Errorf("reading: %w", err)
+	}
+	if len(stops) == 0 {
+		if name != "" {
+			return StopGradient{}, fmt.Errorf("no gradient named (%s) found", name)
+		}
+		return StopGradient{}, fmt.Errorf("no gradient found")
+	}
+	sort.SliceStable(stops, func(i, j int) bool { return stops[i].index < stops[j].index })
+	first, last := stops[0], stops[len(stops)-1]
+	wrapLength := ugrPositions - last.index + first.index
+	wrapColor := mixColors(ColorSpaceSRGB, last.color, first.color, float64(ugrPositions-last.index)/float64(wrapLength))
+	g := StopGradient{ColorSpace: ColorSpaceSRGB}
+	if first.index > 0 {
+		g.Stops = append(g.Stops, GradientStop{Position: 0, Color: wrapColor})
+	}
+	for _, stop := range stops {
+		g.Stops = append(g.Stops, GradientStop{Position: float64(stop.index) / ugrPositions, Color: stop.color})
+	}
+	g.Stops = append(g.Stops, GradientStop{Position: 1, Color: wrapColor})
+	return g, nil
+}
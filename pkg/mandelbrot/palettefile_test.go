@@ -0,0 +1,104 @@
+package mandelbrot
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPaletteFileRoundTrip(t *testing.T) {
+	palette := color.Palette{
+		color.RGBA{R: 255, A: 255},
+		color.RGBA{G: 128, A: 255},
+		color.RGBA{B: 64, A: 255},
+		color.RGBA{R: 1, G: 2, B: 3, A: 255},
+	}
+	for _, format := range []string{PaletteFormatMap, PaletteFormatGPL, PaletteFormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, WritePalette(&buf, format, palette))
+			g, err := ReadPalette(&buf, format, "")
+			require.NoError(t, err)
+			require.Len(t, g.Stops, len(palette))
+			for i, stop := range g.Stops {
+				require.Equal(t, palette[i], stop.Color)
+				require.InDelta(t, float64(i)/3, stop.Position, 1e-9)
+			}
+		})
+	}
+	t.Run(PaletteFormatUGR, func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, WritePalette(&buf, PaletteFormatUGR, palette))
+		g, err := ReadPalette(&buf, PaletteFormatUGR, "")
+		require.NoError(t, err)
+		// The gradient wraps around from the last color back to the first.
+		require.Len(t, g.Stops, len(palette)+1)
+		for i, c := range palette {
+			require.Equal(t, c, g.Stops[i].Color)
+			require.Equal(t, float64(i)/4, g.Stops[i].Position)
+		}
+		require.Equal(t, palette[0], g.Stops[4].Color)
+	})
+}
+
+func TestReadUGRGradient(t *testing.T) {
+	const collection = `first {
+gradient:
+  title="first" smooth=no
+  index=0 color=255
+opacity:
+  smooth=no index=0 opacity=255
+}
+
+second {
+gradient:
+  title="second" smooth=yes
+  index=300 color=16711680
+  index=100 color=65280
+opacity:
+  smooth=no index=0 opacity=255
+}
+`
+	g, err := ReadPalette(strings.NewReader(collection), PaletteFormatUGR, "")
+	require.NoError(t, err)
+	require.Equal(t, color.RGBA{R: 255, A: 255}, g.At(0.5))
+
+	g, err = ReadPalette(strings.NewReader(collection), PaletteFormatUGR, "second")
+	require.NoError(t, err)
+	green := color.RGBA{G: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	// Sorted by index, with the wrap from blue at 300 around to green at 100 added at both ends.
+	require.Len(t, g.Stops, 4)
+	require.Equal(t, 0.0, g.Stops[0].Position)
+	require.Equal(t, green, g.Stops[1].Color)
+	require.Equal(t, 0.25, g.Stops[1].Position)
+	require.Equal(t, blue, g.Stops[2].Color)
+	require.Equal(t, 0.75, g.Stops[2].Position)
+	require.Equal(t, g.Stops[0].Color, g.Stops[3].Color)
+	require.Equal(t, color.RGBA{G: 128, B: 128, A: 255}, g.Stops[0].Color)
+
+	_, err = ReadPalette(strings.NewReader(collection), PaletteFormatUGR, "third")
+	require.EqualError(t, err, "no gradient named (third) found")
+}
+
+func TestReadPaletteErrors(t *testing.T) {
+	_, err := ReadPalette(strings.NewReader("0 0 0\n"), PaletteFormatGPL, "")
+	require.EqualError(t, err, "missing GIMP Palette header")
+	_, err = ReadPalette(strings.NewReader("0 0 0\n0 300 0\n"), PaletteFormatMap, "")
+	require.EqualError(t, err, "line (2): invalid color component (300)")
+	_, err = ReadPalette(strings.NewReader("#000000,#zzzzzz\n"), PaletteFormatCSV, "")
+	require.EqualError(t, err, "color (1): invalid color (#zzzzzz)")
+}
+
+func TestWritePaletteUCL(t *testing.T) {
+	palette := color.Palette{color.RGBA{R: 255, A: 255}, color.RGBA{A: 255}}
+	require.EqualError(t, WritePalette(&bytes.Buffer{}, PaletteFormatUCL, palette), "writing palette format (ucl) is not supported")
+	filePath := filepath.Join(t.TempDir(), "brot.ucl")
+	require.EqualError(t, WritePaletteFile(filePath, palette), "writing palette format (ucl) is not supported")
+	_, err := os.Stat(filePath)
+	require.True(t, os.IsNotExist(err))
+}
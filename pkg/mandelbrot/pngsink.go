@@ -0,0 +1,52 @@
+package mandelbrot
+
+import (
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+)
+
+// PNGSequenceSink writes every frame as a numbered PNG file to a directory.
+// Frames are numbered from 1: frame_00001.png, frame_00002.png, and so on.
+type PNGSequenceSink struct {
+	dir     string
+	encoder png.Encoder
+}
+
+// NewPNGSequenceSink creates the directory if it does not exist yet.
+func NewPNGSequenceSink(dir string) (*PNGSequenceSink, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating directory: %w", err)
+	}
+	return &PNGSequenceSink{
+		dir: dir,
+		encoder: png.Encoder{
+			CompressionLevel: png.BestSpeed,
+		},
+	}, nil
+}
+
+func (s *PNGSequenceSink) FramePath(index int) string {
+	return filepath.Join(s.dir, fmt.Sprintf("frame_%05d.png", index+1))
+}
+
+func (s *PNGSequenceSink) WriteFrame(frame Frame) error {
+	filePath := s.FramePath(frame.Index)
+	f, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("creating frame file: %w", err)
+	}
+	if err := s.encoder.Encode(f, frame.Image); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("encoding PNG (%s): %w", filePath, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("closing frame file: %w", err)
+	}
+	return nil
+}
+
+func (s *PNGSequenceSink) Close() error {
+	return nil
+}
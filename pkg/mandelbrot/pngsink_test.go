@@ -0,0 +1,44 @@
+package mandelbrot
+
+import (
+	"github.com/stretchr/testify/require"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPNGSequenceSink(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "frames")
+	sink, err := NewPNGSequenceSink(dir)
+	require.NoError(t, err)
+	palette := Gradient(color.RGBA{B: 255, A: 255}, color.RGBA{A: 255}, 4)
+	paletted := image.NewPaletted(image.Rect(0, 0, 3, 2), palette)
+	for i := range paletted.Pix {
+		paletted.Pix[i] = uint8(i % len(palette))
+	}
+	frames := []image.Image{paletted, solidRGBA(3, 2, color.RGBA{R: 255, G: 128, A: 255})}
+	for i, img := range frames {
+		require.NoError(t, sink.WriteFrame(Frame{Index: i, Delay: 100 * time.Millisecond, Image: img}))
+	}
+	require.NoError(t, sink.Close())
+
+	for i, want := range frames {
+		filePath := filepath.Join(dir, []string{"frame_00001.png", "frame_00002.png"}[i])
+		require.Equal(t, filePath, sink.FramePath(i))
+		f, err := os.Open(filePath)
+		require.NoError(t, err)
+		got, err := png.Decode(f)
+		require.NoError(t, f.Close())
+		require.NoError(t, err)
+		require.Equal(t, want.Bounds(), got.Bounds())
+		for y := 0; y < 2; y++ {
+			for x := 0; x < 3; x++ {
+				require.Equal(t, rgba(want.At(x, y)), rgba(got.At(x, y)), "frame %d pixel (%d,%d)", i, x, y)
+			}
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package mandelbrot
+
+import (
+	"image"
+	"time"
+)
+
+// Frame is a single rendered frame of an animation.
+type Frame struct {
+	// Index is the position of the frame in the full animation, starting at 0.
+	Index int
+	// Count is the number of frames passed to the sink;
+	// when only a range of frames is rendered, this is less than the number of frames in the animation.
+	Count int
+	// Delay is the time the frame is shown before the next one.
+	Delay time.Duration
+	// Image is either an *image.Paletted or an *image.RGBA.
+	Image image.Image
+	// Render contains the parameters the frame was rendered with.
+	Render RenderConfig
+}
+
+// FrameSink receives frames as they are rendered, in order.
+// Close must be called after the last frame, to finalize the output.
+type FrameSink interface {
+	WriteFrame(frame Frame) error
+	Close() error
+}
+
+// TrueColorSink is implemented by sinks that want *image.RGBA frames, rather than paletted ones.
+type TrueColorSink interface {
+	FrameSink
+	TrueColor() bool
+}
+
+// frameRepeater converts frame delays to a number of frames at a fixed frame rate, for sinks that cannot store delays.
+// Like delayScheduler, it rounds the time since the start of the animation,
+// so that frames are repeated or dropped as needed without the total duration drifting.
+type frameRepeater struct {
+	fps     int
+	elapsed time.Duration
+	frames  int
+}
+
+// Next returns the number of times a frame shown for delay is written; this is 0 when it is dropped.
+func (fr *frameRepeater) Next(delay time.Duration) int {
+	fr.elapsed += delay
+	total := int((fr.elapsed*time.Duration(fr.fps) + time.Second/2) / time.Second)
+	repeats := total - fr.frames
+	fr.frames = total
+	return repeats
+}
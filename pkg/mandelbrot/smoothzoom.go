@@ -0,0 +1,98 @@
+package mandelbrot
+
+import (
+	"math"
+)
+
+// smoothZoomRho trades off zooming against panning.
+// Van Wijk and Nuij found sqrt(2) to be a good perceptual fit.
+const smoothZoomRho = math.Sqrt2
+
+// smoothZoom implements the optimal zoom-and-pan trajectory between two views,
+// as described in "Smooth and efficient zooming and panning" by van Wijk and Nuij (2003).
+// Views are handled as a center and width in mandelbrot space, so that distances
+// are measured the same way along both axes.
+type smoothZoom struct {
+	c0x, c0y float64
+	c1x, c1y float64
+	w0, w1   float64
+	// u1 is the distance between both centers.
+	u1 float64
+	// r0 and length parameterize the curve; length is the total path length S.
+	r0     float64
+	length float64
+}
+
+func newSmoothZoom(from, to CameraState) *smoothZoom {
+	sz := &smoothZoom{}
+	sz.c0x, sz.c0y, sz.w0 = cameraView(from)
+	sz.c1x, sz.c1y, sz.w1 = cameraView(to)
+	sz.u1 = math.Hypot(sz.c1x-sz.c0x, sz.c1y-sz.c0y)
+	rho2 := smoothZoomRho * smoothZoomRho
+	if sz.u1 < 1e-12 {
+		sz.length = math.Abs(math.Log(sz.w1/sz.w0)) / smoothZoomRho
+		return sz
+	}
+	b := func(w, sign float64) float64 {
+		return (sz.w1*sz.w1 - sz.w0*sz.w0 + sign*rho2*rho2*sz.u1*sz.u1) / (2 * w * rho2 * sz.u1)
+	}
+	r := func(b float64) float64 {
+		return math.Log(-b + math.Sqrt(b*b+1))
+	}
+	sz.r0 = r(b(sz.w0, 1))
+	r1 := r(b(sz.w1, -1))
+	sz.length = (r1 - sz.r0) / smoothZoomRho
+	return sz
+}
+
+// At returns the view at fraction u (between 0 and 1) of the path.
+func (sz *smoothZoom) At(u float64) CameraState {
+	if u <= 0 {
+		return cameraFromView(sz.c0x, sz.c0y, sz.w0)
+	}
+	if u >= 1 {
+		return cameraFromView(sz.c1x, sz.c1y, sz.w1)
+	}
+	s := u * sz.length
+	rho2 := smoothZoomRho * smoothZoomRho
+	if sz.u1 < 1e-12 {
+		k := 1.0
+		if sz.w1 < sz.w0 {
+			k = -1.0
+		}
+		w := sz.w0 * math.Exp(k*smoothZoomRho*s)
+		return cameraFromView(sz.c0x, sz.c0y, w)
+	}
+	dist := sz.w0/rho2*math.Cosh(sz.r0)*math.Tanh(smoothZoomRho*s+sz.r0) - sz.w0/rho2*math.Sinh(sz.r0)
+	w := sz.w0 * math.Cosh(sz.r0) / math.Cosh(smoothZoomRho*s+sz.r0)
+	frac := dist / sz.u1
+	cx := sz.c0x + (sz.c1x-sz.c0x)*frac
+	cy := sz.c0y + (sz.c1y-sz.c0y)*frac
+	return cameraFromView(cx, cy, w)
+}
+
+// cameraView converts a camera state into the center and width of the visible area in mandelbrot space.
+// The zooming scaler zooms in on the target, rather than centering it,
+// so the visible center lies between the target and the middle of the image.
+func cameraView(cs CameraState) (cx, cy, w float64) {
+	normCX := cs.TargetX + (0.5-cs.TargetX)/cs.Zoom
+	normCY := cs.TargetY + (0.5-cs.TargetY)/cs.Zoom
+	return normCX * (maxX - minX), normCY * (maxY - minY), (maxX - minX) / cs.Zoom
+}
+
+// cameraFromView is the inverse of cameraView.
+// Views wider than the full mandelbrot area are clamped to a zoom of 1.
+func cameraFromView(cx, cy, w float64) CameraState {
+	normW := w / (maxX - minX)
+	if normW >= 1 {
+		return CameraState{Zoom: 1, TargetX: 0.5, TargetY: 0.5}
+	}
+	target := func(normC float64) float64 {
+		return clamp01((normC - 0.5*normW) / (1 - normW))
+	}
+	return CameraState{
+		Zoom:    1 / normW,
+		TargetX: target(cx / (maxX - minX)),
+		TargetY: target(cy / (maxY - minY)),
+	}
+}
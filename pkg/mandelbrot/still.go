@@ -0,0 +1,52 @@
+package mandelbrot
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"time"
+)
+
+// RenderStill renders the view at the given timeline position of the animation as a single true color image.
+func RenderStill(cfg AnimationConfig, palette color.Palette, at time.Duration) (*image.RGBA, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("validating animation config: %w", err)
+	}
+	a, err := newAnimator(cfg, palette, true)
+	if err != nil {
+		return nil, err
+	}
+	return a.still(at)
+}
+
+// still renders the view at timeline position at, without motion blur.
+func (a *animator) still(at time.Duration) (*image.RGBA, error) {
+	if at < 0 || at > a.duration {
+		return nil, fmt.Errorf("position (%v) is outside of the animation (%v)", at, a.duration)
+	}
+	renderCfg, err := a.cfg.renderConfig(a.cameraPath.At(at))
+	if err != nil {
+		return nil, fmt.Errorf("determining render config: %w", err)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, a.cfg.Width, a.cfg.Height))
+	if err := RenderRGBA(renderCfg, img, a.cfg.paletteAt(a.palettes, at)); err != nil {
+		return nil, fmt.Errorf("rendering: %w", err)
+	}
+	if len(a.cfg.Overlays) > 0 {
+		return drawOverlays(img, a.cfg.Overlays, overlayInfo{
+			Render: renderCfg,
+			Frame:  a.stillFrameIndex(at),
+			Frames: a.FrameCount(),
+		}).(*image.RGBA), nil
+	}
+	return img, nil
+}
+
+// stillFrameIndex returns the index of the frame that shows timeline position at.
+// The end of the animation is only shown by the closing frame, if there is one.
+func (a *animator) stillFrameIndex(at time.Duration) int {
+	if at == a.duration && a.closingFrame() {
+		return a.timeline.FrameCount()
+	}
+	return a.timeline.FrameIndex(at)
+}
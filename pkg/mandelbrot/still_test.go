@@ -0,0 +1,44 @@
+package mandelbrot
+
+import (
+	"github.com/stretchr/testify/require"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestStillFrameIndex(t *testing.T) {
+	path := []AnimationConfigPathElement{
+		{Zoom: 1, TargetX: 0.5, TargetY: 0.5},
+		{Zoom: 4, TargetX: 0.4, TargetY: 0.4, Duration: 400 * time.Millisecond},
+	}
+	var tests = []struct {
+		desc       string
+		cfg        AnimationConfig
+		at         time.Duration
+		wantFrame  int
+		wantFrames int
+	}{
+		{desc: "loop", cfg: AnimationConfig{Path: path}, at: 400 * time.Millisecond, wantFrame: 3, wantFrames: 4},
+		{desc: "once", cfg: AnimationConfig{Path: path, LoopCount: -1}, at: 400 * time.Millisecond, wantFrame: 4, wantFrames: 5},
+		{desc: "once before the end", cfg: AnimationConfig{Path: path, LoopCount: -1}, at: 250 * time.Millisecond, wantFrame: 2, wantFrames: 5},
+		{desc: "ping-pong", cfg: AnimationConfig{Path: path, LoopMode: LoopModePingPong}, at: 100 * time.Millisecond, wantFrame: 1, wantFrames: 8},
+		{desc: "cycle only", cfg: AnimationConfig{Path: path[:1], CycleSpeed: 40}, at: 200 * time.Millisecond, wantFrame: 2, wantFrames: 4},
+	}
+	palette := Gradient(color.RGBA{B: 255, A: 255}, color.Black, 16)
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			cfg := test.cfg
+			cfg.Width, cfg.Height, cfg.FPS, cfg.MaxIterations = 8, 6, 10, 50
+			a, err := newAnimator(cfg, palette, true)
+			require.NoError(t, err)
+			require.Equal(t, test.wantFrame, a.stillFrameIndex(test.at))
+			require.Equal(t, test.wantFrames, a.FrameCount())
+			count, err := FrameCount(cfg, palette)
+			require.NoError(t, err)
+			require.Equal(t, test.wantFrames, count)
+			_, err = RenderStill(cfg, palette, test.at)
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package mandelbrot
+
+import (
+	"time"
+)
+
+// Timeline divides an animation into frames at a fixed frame rate.
+// Frame times are calculated from the frame index, rather than by adding up frame durations,
+// so that rounding errors do not accumulate and the frames add up to exactly the animation duration.
+type Timeline struct {
+	duration   time.Duration
+	fps        int
+	frameCount int
+}
+
+// NewTimeline creates a timeline of the given duration.
+// A timeline without duration consists of a single frame.
+func NewTimeline(duration time.Duration, fps int) Timeline {
+	frameCount := 1
+	if duration > 0 {
+		// Round up, so that the frames cover the entire duration;
+		// the last frame may be shorter than the others.
+		perSecond := duration * time.Duration(fps)
+		frameCount = int(perSecond / time.Second)
+		if perSecond%time.Second != 0 {
+			frameCount++
+		}
+	}
+	return Timeline{
+		duration:   duration,
+		fps:        fps,
+		frameCount: frameCount,
+	}
+}
+
+func (tl Timeline) FrameCount() int {
+	return tl.frameCount
+}
+
+// Duration returns the total time all frames are shown.
+func (tl Timeline) Duration() time.Duration {
+	if tl.duration == 0 {
+		return tl.FrameDelay(0)
+	}
+	return tl.duration
+}
+
+// FrameTime returns the position of the frame on the timeline.
+func (tl Timeline) FrameTime(index int) time.Duration {
+	return time.Duration(index) * time.Second / time.Duration(tl.fps)
+}
+
+// FrameDelay returns how long the frame is shown.
+func (tl Timeline) FrameDelay(index int) time.Duration {
+	next := tl.FrameTime(index + 1)
+	if tl.duration > 0 && next > tl.duration {
+		next = tl.duration
+	}
+	return next - tl.FrameTime(index)
+}
+
+// FrameIndex returns the index of the frame that is shown at timeline position t.
+func (tl Timeline) FrameIndex(t time.Duration) int {
+	index := int(t * time.Duration(tl.fps) / time.Second)
+	if index >= tl.frameCount {
+		index = tl.frameCount - 1
+	}
+	return index
+}
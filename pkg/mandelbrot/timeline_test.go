@@ -0,0 +1,59 @@
+package mandelbrot
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestTimeline(t *testing.T) {
+	var tests = []struct {
+		desc       string
+		duration   time.Duration
+		fps        int
+		frameCount int
+	}{
+		{desc: "still", duration: 0, fps: 30, frameCount: 1},
+		{desc: "20fps", duration: 2 * time.Second, fps: 20, frameCount: 40},
+		{desc: "30fps", duration: 2 * time.Second, fps: 30, frameCount: 60},
+		{desc: "40fps", duration: 3 * time.Second, fps: 40, frameCount: 120},
+		{desc: "60fps", duration: 1500 * time.Millisecond, fps: 60, frameCount: 90},
+		{desc: "partial last frame", duration: 2050 * time.Millisecond, fps: 30, frameCount: 62},
+		{desc: "24fps odd duration", duration: 1234 * time.Millisecond, fps: 24, frameCount: 30},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			tl := NewTimeline(test.duration, test.fps)
+			require.Equal(t, test.frameCount, tl.FrameCount())
+			var total time.Duration
+			var ds delayScheduler
+			ticks := 0
+			for i := 0; i < tl.FrameCount(); i++ {
+				require.Equal(t, total, tl.FrameTime(i))
+				delay := tl.FrameDelay(i)
+				require.Greater(t, delay, time.Duration(0))
+				total += delay
+				frameTicks := ds.Next(delay)
+				require.GreaterOrEqual(t, frameTicks, gifMinDelay)
+				ticks += frameTicks
+				if test.fps <= 50 {
+					// The GIF timing never drifts more than half a tick from the exact timing.
+					require.InDelta(t, total.Seconds(), float64(ticks)/100, 0.005+1e-9)
+				}
+			}
+			require.Equal(t, tl.Duration(), total)
+			if test.duration > 0 {
+				require.Equal(t, test.duration, total)
+			}
+		})
+	}
+}
+
+func TestDelaySchedulerMinimum(t *testing.T) {
+	var ds delayScheduler
+	// A 5ms delay would round to 0 or 1 ticks, which browsers play at 100ms.
+	require.Equal(t, gifMinDelay, ds.Next(5*time.Millisecond))
+	// The next frame is shortened to catch up.
+	require.Equal(t, 2, ds.Next(35*time.Millisecond))
+	require.Equal(t, 3, ds.Next(30*time.Millisecond))
+}
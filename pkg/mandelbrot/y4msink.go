@@ -0,0 +1,84 @@
+package mandelbrot
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+)
+
+// Y4MSink writes frames as an uncompressed YUV4MPEG2 stream, which most video encoders accept as input.
+// Frames are stored with 4:4:4 chroma and full range BT.601 colors, so no detail is lost in the conversion.
+// The stream has a fixed frame rate, so frames are repeated or dropped to match their delays.
+type Y4MSink struct {
+	w             *bufio.Writer
+	width         int
+	height        int
+	fps           int
+	headerWritten bool
+	planes        []byte
+	repeater      frameRepeater
+}
+
+func NewY4MSink(w io.Writer, width, height, fps int) *Y4MSink {
+	return &Y4MSink{
+		w:      bufio.NewWriter(w),
+		width:  width,
+		height: height,
+		fps:    fps,
+		planes: make([]byte, 3*width*height),
+		repeater: frameRepeater{
+			fps: fps,
+		},
+	}
+}
+
+func (s *Y4MSink) WriteFrame(frame Frame) error {
+	if err := s.writeHeader(); err != nil {
+		return fmt.Errorf("writing header: %w", err)
+	}
+	b := frame.Image.Bounds()
+	if b.Dx() != s.width || b.Dy() != s.height {
+		return fmt.Errorf("frame size (%d,%d) does not match stream size (%d,%d)", b.Dx(), b.Dy(), s.width, s.height)
+	}
+	repeats := s.repeater.Next(frame.Delay)
+	if repeats == 0 {
+		return nil
+	}
+	planeSize := s.width * s.height
+	for y := 0; y < s.height; y++ {
+		for x := 0; x < s.width; x++ {
+			r, g, bl, _ := frame.Image.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			yy, cb, cr := color.RGBToYCbCr(uint8(r>>8), uint8(g>>8), uint8(bl>>8))
+			i := y*s.width + x
+			s.planes[i] = yy
+			s.planes[planeSize+i] = cb
+			s.planes[2*planeSize+i] = cr
+		}
+	}
+	for i := 0; i < repeats; i++ {
+		if _, err := s.w.WriteString("FRAME\n"); err != nil {
+			return fmt.Errorf("writing frame (%d) header: %w", frame.Index, err)
+		}
+		if _, err := s.w.Write(s.planes); err != nil {
+			return fmt.Errorf("writing frame (%d): %w", frame.Index, err)
+		}
+	}
+	return nil
+}
+
+func (s *Y4MSink) Close() error {
+	if err := s.writeHeader(); err != nil {
+		return fmt.Errorf("writing header: %w", err)
+	}
+	return s.w.Flush()
+}
+
+func (s *Y4MSink) writeHeader() error {
+	if s.headerWritten {
+		return nil
+	}
+	s.headerWritten = true
+	_, err := fmt.Fprintf(s.w, "YUV4MPEG2 W%d H%d F%d:1 Ip A1:1 C444 XCOLORRANGE=FULL\n", s.width, s.height, s.fps)
+	return err
+}
@@ -0,0 +1,61 @@
+package mandelbrot
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+	"time"
+)
+
+// solidRGBA returns an image filled with a single color.
+func solidRGBA(width, height int, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	return img
+}
+
+func TestY4MSink(t *testing.T) {
+	const width, height = 4, 2
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	green := color.RGBA{G: 255, A: 255}
+	var buf bytes.Buffer
+	sink := NewY4MSink(&buf, width, height, 10)
+	frames := []struct {
+		c     color.RGBA
+		delay time.Duration
+	}{
+		{c: red, delay: 100 * time.Millisecond},
+		// Shown for two frame periods, so it is written twice.
+		{c: blue, delay: 200 * time.Millisecond},
+		// Shown for less than half a frame period, so it is dropped.
+		{c: green, delay: 40 * time.Millisecond},
+	}
+	for i, frame := range frames {
+		require.NoError(t, sink.WriteFrame(Frame{Index: i, Delay: frame.delay, Image: solidRGBA(width, height, frame.c)}))
+	}
+	require.NoError(t, sink.Close())
+
+	data := buf.Bytes()
+	end := bytes.IndexByte(data, '\n')
+	require.Positive(t, end)
+	require.Equal(t, []string{"YUV4MPEG2", "W4", "H2", "F10:1", "Ip", "A1:1", "C444", "XCOLORRANGE=FULL"}, strings.Fields(string(data[:end])))
+	data = data[end+1:]
+	const planeSize = width * height
+	for i, c := range []color.RGBA{red, blue, blue} {
+		require.True(t, bytes.HasPrefix(data, []byte("FRAME\n")), "frame %d", i)
+		data = data[len("FRAME\n"):]
+		require.GreaterOrEqual(t, len(data), 3*planeSize, "frame %d", i)
+		y, cb, cr := color.RGBToYCbCr(c.R, c.G, c.B)
+		require.Equal(t, bytes.Repeat([]byte{y}, planeSize), data[:planeSize], "frame %d", i)
+		require.Equal(t, bytes.Repeat([]byte{cb}, planeSize), data[planeSize:2*planeSize], "frame %d", i)
+		require.Equal(t, bytes.Repeat([]byte{cr}, planeSize), data[2*planeSize:3*planeSize], "frame %d", i)
+		data = data[3*planeSize:]
+	}
+	require.Empty(t, data)
+}